@@ -0,0 +1,86 @@
+// Package assets holds the page templates and static files, built into the
+// binary so the server runs from any directory.
+package assets
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+//go:embed templates static
+var files embed.FS
+
+// Vendored lists the third-party files under static, pinned by
+// fetch-vendor.sh, that pages load instead of fetching them from CDNs.
+var Vendored = []string{
+	"vendor/bootstrap-5.3.2.min.css",
+	"vendor/htmx-1.9.6.min.js",
+	"vendor/htmx-ext-sse-1.9.6.js",
+}
+
+// MissingVendored returns the Vendored files that static does not have.
+func MissingVendored(static fs.FS) []string {
+	var missing []string
+	for _, name := range Vendored {
+		if _, err := fs.Stat(static, name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// CheckVendored fails when static is missing any of the Vendored files, as
+// pages do nothing without them.
+func CheckVendored(static fs.FS) error {
+	missing := MissingVendored(static)
+	if len(missing) > 0 {
+		return fmt.Errorf("static files %s are missing, run assets/fetch-vendor.sh to download them", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Templates returns the page templates.
+func Templates(override string) fs.FS {
+	return open("templates", override)
+}
+
+// Static returns the files served under /static/.
+func Static(override string) fs.FS {
+	return open("static", override)
+}
+
+// open returns the embedded directory dir. When override is set, files in
+// override/dir are used instead of the embedded files of the same name, so
+// that a deployment can restyle the game without rebuilding it.
+func open(dir string, override string) fs.FS {
+	embedded, err := fs.Sub(files, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	if override == "" {
+		return embedded
+	}
+
+	return overlayFS{over: os.DirFS(path.Join(override, dir)), base: embedded}
+}
+
+// overlayFS opens files from over when they exist there, and from base
+// otherwise.
+type overlayFS struct {
+	over fs.FS
+	base fs.FS
+}
+
+func (overlay overlayFS) Open(name string) (fs.File, error) {
+	file, err := overlay.over.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return overlay.base.Open(name)
+	}
+	return file, err
+}
@@ -0,0 +1,60 @@
+package assets
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTemplates_Embedded(t *testing.T) {
+	if _, err := fs.Stat(Templates(""), "index.html"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.Stat(Static(""), "index.css"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTemplates_Override(t *testing.T) {
+	override := t.TempDir()
+	os.Mkdir(filepath.Join(override, "templates"), 0o755)
+	os.WriteFile(filepath.Join(override, "templates", "endPage.html"), []byte("themed"), 0o644)
+
+	templates := Templates(override)
+
+	themed, err := fs.ReadFile(templates, "endPage.html")
+	if err != nil || string(themed) != "themed" {
+		t.Fatal(string(themed), err)
+	}
+
+	// Files the override leaves out are still embedded.
+	if _, err := fs.ReadFile(templates, "index.html"); err != nil {
+		t.Fatal(err)
+	}
+
+	// As is everything when the override has no directory for them.
+	if _, err := fs.ReadFile(Static(override), "index.css"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckVendored(t *testing.T) {
+	static := fstest.MapFS{Vendored[0]: &fstest.MapFile{Data: []byte("/* pinned */")}}
+
+	err := CheckVendored(static)
+	if err == nil || strings.Contains(err.Error(), Vendored[0]) || !strings.Contains(err.Error(), Vendored[1]) {
+		t.Fatal(err)
+	}
+
+	for _, name := range Vendored {
+		static[name] = &fstest.MapFile{Data: []byte("/* pinned */")}
+	}
+
+	if err := CheckVendored(static); err != nil {
+		t.Fatal(err)
+	}
+}
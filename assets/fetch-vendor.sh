@@ -0,0 +1,34 @@
+#!/bin/sh
+# Downloads the pinned front-end files into assets/static/vendor, checking
+# them against their published integrity hashes where they have one. The
+# files are committed, so this is only needed to upgrade them: change the
+# versions here and in assets.Vendored, run it and commit the results. The
+# server embeds them and never loads anything from a CDN.
+set -eu
+
+cd "$(dirname "$0")/static"
+mkdir -p vendor
+
+fetch() {
+    name=$1
+    url=$2
+    integrity=$3
+
+    curl -fsSL -o "$name.tmp" "$url"
+
+    actual="sha384-$(openssl dgst -sha384 -binary "$name.tmp" | openssl base64 -A)"
+    if [ -n "$integrity" ] && [ "$actual" != "$integrity" ]; then
+        rm "$name.tmp"
+        echo "$name: expected $integrity, got $actual" >&2
+        exit 1
+    fi
+
+    mv "$name.tmp" "$name"
+    echo "$name $actual"
+}
+
+fetch vendor/bootstrap-5.3.2.min.css https://cdn.jsdelivr.net/npm/bootstrap@5.3.2/dist/css/bootstrap.min.css sha384-T3c6CoIi6uLrA9TneNEoa7RxnatzjcDSCmG1MXxSR1GAsXEV/Dwwykc2MPK8M2HN
+fetch vendor/htmx-1.9.6.min.js https://unpkg.com/htmx.org@1.9.6/dist/htmx.min.js sha384-FhXw7b6AlE/jyjlZH5iHa/tTe9EpJ1Y55RjcgPbjeWMskSxZt1v9qkxLJWNJaGni
+# htmx publishes no integrity hash for its extensions, so this one is pinned
+# by version only.
+fetch vendor/htmx-ext-sse-1.9.6.js https://unpkg.com/htmx.org@1.9.6/dist/ext/sse.js ""
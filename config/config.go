@@ -0,0 +1,277 @@
+// Package config loads the server's settings. Each setting has a default,
+// which an optional JSON file overrides, then environment variables, then
+// command line flags.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"me885/fintech-or-furniture/quiz"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is everything the server can be configured with. Field tags give
+// the JSON file's keys.
+type Config struct {
+	// Addr is the address the server listens on.
+	Addr string `json:"addr"`
+	// DatabaseURL is a SQLite filename or a postgres:// connection string.
+	DatabaseURL string `json:"databaseUrl"`
+	// AdminUsers are the admins' credentials, as "alice:secret,bob:hunter2".
+	AdminUsers string `json:"adminUsers"`
+	// AssetsDir overrides embedded templates and static files, when set.
+	AssetsDir      string `json:"assetsDir"`
+	TemplateReload bool   `json:"templateReload"`
+	SecureCookies  bool   `json:"secureCookies"`
+	// GameSessionLifetime is how long a game session lasts after its last
+	// new game, and PlayerSessionLifetime how long players stay logged in.
+	GameSessionLifetime   Duration `json:"gameSessionLifetime"`
+	PlayerSessionLifetime Duration `json:"playerSessionLifetime"`
+	// QuestionCounts changes how many questions game modes ask.
+	QuestionCounts QuestionCounts `json:"questionCounts"`
+	// Selector picks games' questions in modes that don't pick their own,
+	// as read by quiz.ParseSelector.
+	Selector string `json:"selector"`
+}
+
+// Default is the configuration used where nothing else is given.
+func Default() Config {
+	return Config{
+		Addr:                  ":8002",
+		DatabaseURL:           "sqlite.db",
+		Selector:              "difficulty",
+		GameSessionLifetime:   Duration(quiz.GameSessionLifetime),
+		PlayerSessionLifetime: Duration(quiz.SessionLifetime),
+	}
+}
+
+// Load builds the configuration from the command line args and environment
+// variables read with getenv. The JSON file is named by -config or
+// CONFIG_FILE. It returns the arguments left after the flags.
+func Load(args []string, getenv func(string) string) (*Config, []string, error) {
+	// Parse the flags once to find the file, and again after the file and
+	// environment so that they take precedence.
+	var file string
+	defaults := Default()
+	if err := newFlagSet(&defaults, &file).Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if file == "" {
+		file = getenv("CONFIG_FILE")
+	}
+
+	config := Default()
+
+	if file != "" {
+		if err := config.loadFile(file); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := config.loadEnv(getenv); err != nil {
+		return nil, nil, err
+	}
+
+	flags := newFlagSet(&config, &file)
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return &config, flags.Args(), nil
+}
+
+func newFlagSet(config *Config, file *string) *flag.FlagSet {
+	flags := flag.NewFlagSet("fintech-or-furniture", flag.ContinueOnError)
+	flags.StringVar(file, "config", *file, "JSON file to read settings from")
+	flags.StringVar(&config.Addr, "addr", config.Addr, "address to listen on")
+	flags.StringVar(&config.DatabaseURL, "db", config.DatabaseURL, "SQLite filename or postgres:// connection string")
+	flags.StringVar(&config.AssetsDir, "assets", config.AssetsDir, "directory of templates and static files to use in place of the embedded ones")
+	flags.BoolVar(&config.TemplateReload, "reload", config.TemplateReload, "parse templates again on every request")
+	flags.BoolVar(&config.SecureCookies, "secure-cookies", config.SecureCookies, "only send cookies over HTTPS")
+	flags.Var(&config.GameSessionLifetime, "game-session-lifetime", "how long game sessions last after their last new game")
+	flags.Var(&config.PlayerSessionLifetime, "player-session-lifetime", "how long players stay logged in")
+	flags.Var(&config.QuestionCounts, "question-counts", "questions per game by mode, as classic=15,quick=3")
+	flags.StringVar(&config.Selector, "selector", config.Selector, "how questions are picked: difficulty, uniform, balanced or sequence:<ids>")
+	return flags
+}
+
+func (config *Config) loadFile(name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	return nil
+}
+
+func (config *Config) loadEnv(getenv func(string) string) error {
+	for _, setting := range []struct {
+		name  string
+		value flag.Value
+	}{
+		{"LISTEN_ADDR", (*stringValue)(&config.Addr)},
+		{"DATABASE_URL", (*stringValue)(&config.DatabaseURL)},
+		{"ADMIN_USERS", (*stringValue)(&config.AdminUsers)},
+		{"ASSETS_DIR", (*stringValue)(&config.AssetsDir)},
+		{"TEMPLATE_RELOAD", (*boolValue)(&config.TemplateReload)},
+		{"SECURE_COOKIES", (*boolValue)(&config.SecureCookies)},
+		{"GAME_SESSION_LIFETIME", &config.GameSessionLifetime},
+		{"PLAYER_SESSION_LIFETIME", &config.PlayerSessionLifetime},
+		{"QUESTION_COUNTS", &config.QuestionCounts},
+		{"QUESTION_SELECTOR", (*stringValue)(&config.Selector)},
+	} {
+		value := getenv(setting.name)
+		if value == "" {
+			continue
+		}
+		if err := setting.value.Set(value); err != nil {
+			return fmt.Errorf("%s: %w", setting.name, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks every setting, returning all the problems found.
+func (config *Config) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(config.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("addr: %w", err))
+	}
+
+	if config.DatabaseURL == "" {
+		errs = append(errs, errors.New("databaseUrl is required"))
+	}
+
+	for _, pair := range strings.Split(config.AdminUsers, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		if username, password, ok := strings.Cut(pair, ":"); !ok || username == "" || password == "" {
+			errs = append(errs, fmt.Errorf("adminUsers: %q should be username:password", username))
+		}
+	}
+
+	if config.GameSessionLifetime <= 0 {
+		errs = append(errs, errors.New("gameSessionLifetime should be positive"))
+	}
+
+	if config.PlayerSessionLifetime <= 0 {
+		errs = append(errs, errors.New("playerSessionLifetime should be positive"))
+	}
+
+	for _, mode := range config.QuestionCounts.modes() {
+		if err := quiz.GameModes.CheckQuestionCount(mode, config.QuestionCounts[mode]); err != nil {
+			errs = append(errs, fmt.Errorf("questionCounts: %w", err))
+		}
+	}
+
+	if _, err := quiz.ParseSelector(config.Selector); err != nil {
+		errs = append(errs, fmt.Errorf("selector: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Duration is a time.Duration written like "168h" in files, variables and
+// flags.
+type Duration time.Duration
+
+func (duration Duration) String() string {
+	return time.Duration(duration).String()
+}
+
+func (duration *Duration) Set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*duration = Duration(parsed)
+	return nil
+}
+
+func (duration *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return duration.Set(value)
+}
+
+// QuestionCounts maps game modes to how many questions they ask, written
+// like "classic=15,quick=3" in variables and flags.
+type QuestionCounts map[string]int64
+
+func (counts QuestionCounts) String() string {
+	var pairs []string
+	for _, mode := range counts.modes() {
+		pairs = append(pairs, mode+"="+strconv.FormatInt(counts[mode], 10))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (counts *QuestionCounts) Set(value string) error {
+	parsed := QuestionCounts{}
+	for _, pair := range strings.Split(value, ",") {
+		mode, count, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		number, err := strconv.ParseInt(count, 10, 64)
+		if !ok || err != nil {
+			return fmt.Errorf("%q should be mode=count", pair)
+		}
+		parsed[mode] = number
+	}
+	*counts = parsed
+	return nil
+}
+
+func (counts QuestionCounts) modes() []string {
+	var modes []string
+	for mode := range counts {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	return modes
+}
+
+type stringValue string
+
+func (value *stringValue) String() string {
+	return string(*value)
+}
+
+func (value *stringValue) Set(s string) error {
+	*value = stringValue(s)
+	return nil
+}
+
+type boolValue bool
+
+func (value *boolValue) String() string {
+	return strconv.FormatBool(bool(*value))
+}
+
+func (value *boolValue) Set(s string) error {
+	parsed, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*value = boolValue(parsed)
+	return nil
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+func writeConfigFile(t *testing.T, contents string) string {
+	name := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(name, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	config, args, err := Load(nil, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(*config, Default()) || len(args) != 0 {
+		t.Fatal(config, args)
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	file := writeConfigFile(t, `{"addr": ":9000", "databaseUrl": "file.db", "assetsDir": "theme", "gameSessionLifetime": "48h"}`)
+
+	config, args, err := Load(
+		[]string{"-config", file, "-db", "flag.db", "migrate"},
+		env(map[string]string{"DATABASE_URL": "env.db", "LISTEN_ADDR": ":9001", "SECURE_COOKIES": "true"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.AssetsDir != "theme" || time.Duration(config.GameSessionLifetime) != 48*time.Hour {
+		t.Fatal("the file should override the defaults", config)
+	}
+	if config.Addr != ":9001" || !config.SecureCookies {
+		t.Fatal("the environment should override the file", config)
+	}
+	if config.DatabaseURL != "flag.db" {
+		t.Fatal("flags should override the environment", config)
+	}
+	if len(args) != 1 || args[0] != "migrate" {
+		t.Fatal(args)
+	}
+}
+
+func TestLoad_FileFromEnvironment(t *testing.T) {
+	file := writeConfigFile(t, `{"questionCounts": {"classic": 15}}`)
+
+	config, _, err := Load(nil, env(map[string]string{"CONFIG_FILE": file}))
+	if err != nil || config.QuestionCounts["classic"] != 15 {
+		t.Fatal(config, err)
+	}
+}
+
+func TestLoad_Selector(t *testing.T) {
+	config, _, err := Load([]string{"-selector", "sequence:3,1"}, env(map[string]string{"QUESTION_SELECTOR": "balanced"}))
+	if err != nil || config.Selector != "sequence:3,1" {
+		t.Fatal(config, err)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		env  map[string]string
+		want string
+	}{
+		{[]string{"-addr", "8002"}, nil, "addr"},
+		{[]string{"-db", ""}, nil, "databaseUrl"},
+		{nil, map[string]string{"ADMIN_USERS": "alice"}, "adminUsers"},
+		{nil, map[string]string{"SECURE_COOKIES": "maybe"}, "SECURE_COOKIES"},
+		{[]string{"-game-session-lifetime", "-1h"}, nil, "gameSessionLifetime"},
+		{[]string{"-question-counts", "survival=10"}, nil, "questionCounts"},
+		{[]string{"-question-counts", "classic"}, nil, "mode=count"},
+		{nil, map[string]string{"QUESTION_SELECTOR": "random"}, "selector"},
+		{[]string{"-config", writeConfigFile(t, `{"port": 8002}`)}, nil, "unknown field"},
+		{[]string{"-unknown"}, nil, "not defined"},
+	} {
+		_, _, err := Load(test.args, env(test.env))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Error(test.args, test.env, err)
+		}
+	}
+}
+
+func TestLoad_ReportsEveryProblem(t *testing.T) {
+	_, _, err := Load([]string{"-addr", "nope", "-player-session-lifetime", "0s"}, env(nil))
+	if err == nil || !strings.Contains(err.Error(), "addr") || !strings.Contains(err.Error(), "playerSessionLifetime") {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad_Help(t *testing.T) {
+	if _, _, err := Load([]string{"-h"}, env(nil)); !errors.Is(err, flag.ErrHelp) {
+		t.Fatal(err)
+	}
+}
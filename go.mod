@@ -4,5 +4,9 @@ go 1.21.3
 
 require (
 	github.com/google/uuid v1.5.0
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.18
+	golang.org/x/crypto v0.17.0
 )
+
+require golang.org/x/sys v0.15.0 // indirect
@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"me885/fintech-or-furniture/quiz"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const adminPageSize = 20
+
+// ParseAdmins reads admin credentials in the form "alice:secret,bob:hunter2".
+func ParseAdmins(value string) map[string]string {
+	admins := map[string]string{}
+
+	for _, pair := range strings.Split(value, ",") {
+		username, password, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && username != "" && password != "" {
+			admins[username] = password
+		}
+	}
+
+	return admins
+}
+
+// requireAdmin checks the request's basic auth credentials and returns the
+// admin's username, asking the browser to log in if they are missing.
+func (context Context) requireAdmin(writer http.ResponseWriter, request *http.Request) (string, bool) {
+	username, password, ok := request.BasicAuth()
+
+	expected, known := context.Admins[username]
+	if ok && known && subtle.ConstantTimeCompare([]byte(password), []byte(expected)) == 1 {
+		return username, true
+	}
+
+	writer.Header().Set("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+	http.Error(writer, "admin credentials required", http.StatusUnauthorized)
+	return "", false
+}
+
+// requireHtmx rejects changes that were not made by htmx. Browsers send
+// basic auth credentials with any request to the site, so this stops other
+// sites posting forms to the admin console.
+func requireHtmx(writer http.ResponseWriter, request *http.Request) bool {
+	if request.Header.Get("HX-Request") != "true" {
+		http.Error(writer, "changes must be made from the admin console", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+func (context Context) AdminPage(writer http.ResponseWriter, request *http.Request) {
+	if _, ok := context.requireAdmin(writer, request); !ok {
+		return
+	}
+
+	table, err := context.adminQuestionTable("", 1)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	context.render(writer, "admin.html", table)
+}
+
+var adminQuestionPathRegex = regexp.MustCompile(`^/admin/questions/(?:([0-9]+)/(?:(edit|enable|disable)/)?)?$`)
+
+// AdminQuestions serves everything under /admin/questions/:
+//
+//	GET    /admin/questions/?search=&page=  the question table
+//	POST   /admin/questions/                create a question
+//	GET    /admin/questions/{id}/           a question's row
+//	POST   /admin/questions/{id}/           update a question
+//	DELETE /admin/questions/{id}/           delete a question
+//	GET    /admin/questions/{id}/edit/      a question's row as a form
+//	POST   /admin/questions/{id}/enable/    put a question back in play
+//	POST   /admin/questions/{id}/disable/   stop a question being served
+func (context Context) AdminQuestions(writer http.ResponseWriter, request *http.Request) {
+	admin, ok := context.requireAdmin(writer, request)
+	if !ok {
+		return
+	}
+
+	match := adminQuestionPathRegex.FindStringSubmatch(request.URL.Path)
+	if match == nil {
+		http.NotFound(writer, request)
+		return
+	}
+
+	if request.Method != http.MethodGet && !requireHtmx(writer, request) {
+		return
+	}
+
+	if match[1] == "" {
+		switch request.Method {
+		case http.MethodGet:
+			context.adminListQuestions(writer, request)
+		case http.MethodPost:
+			context.adminCreateQuestion(writer, request, admin)
+		default:
+			http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	id, _ := strconv.ParseInt(match[1], 10, 64)
+
+	question, err := context.DB.GetQuestionById(id)
+	if errors.Is(err, quiz.ErrNotExists) {
+		http.NotFound(writer, request)
+		return
+	}
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case match[2] == "" && request.Method == http.MethodGet:
+		context.renderAdminRow(writer, "adminQuestionRow.html", *question, "")
+	case match[2] == "" && request.Method == http.MethodPost:
+		context.adminUpdateQuestion(writer, request, admin, *question)
+	case match[2] == "" && request.Method == http.MethodDelete:
+		context.adminDeleteQuestion(writer, admin, *question)
+	case match[2] == "edit" && request.Method == http.MethodGet:
+		context.renderAdminRow(writer, "adminQuestionEditRow.html", *question, "")
+	case (match[2] == "enable" || match[2] == "disable") && request.Method == http.MethodPost:
+		context.adminSetDisabled(writer, admin, *question, match[2] == "disable")
+	default:
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (context Context) AdminAudit(writer http.ResponseWriter, request *http.Request) {
+	if _, ok := context.requireAdmin(writer, request); !ok {
+		return
+	}
+
+	entries, err := context.DB.AuditEntries(50)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	context.render(writer, "adminAudit.html", entries)
+}
+
+func (context Context) adminQuestionTable(search string, page int) (*quiz.AdminQuestionTableStruct, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	packs, packsById, err := context.adminPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	questions, total, err := context.DB.ListQuestions(search, (page-1)*adminPageSize, adminPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []quiz.AdminQuestionRowStruct
+	for _, question := range questions {
+		rows = append(rows, quiz.AdminQuestionRowStruct{Question: question, Pack: packsById[question.PackId], Packs: packs})
+	}
+
+	pages := int((total + adminPageSize - 1) / adminPageSize)
+	if pages < 1 {
+		pages = 1
+	}
+
+	table := &quiz.AdminQuestionTableStruct{Rows: rows, Packs: packs, Search: search, Total: total, Page: page, Pages: pages}
+	if page > 1 {
+		table.PreviousPage = page - 1
+	}
+	if page < pages {
+		table.NextPage = page + 1
+	}
+
+	return table, nil
+}
+
+func (context Context) adminListQuestions(writer http.ResponseWriter, request *http.Request) {
+	page, _ := strconv.Atoi(request.URL.Query().Get("page"))
+
+	table, err := context.adminQuestionTable(request.URL.Query().Get("search"), page)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	context.renderAdminTable(writer, table)
+}
+
+func (context Context) adminCreateQuestion(writer http.ResponseWriter, request *http.Request, admin string) {
+	_, packsById, err := context.adminPacks()
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	question, formErr := questionFromForm(request, quiz.Question{PackId: quiz.DefaultPackId}, packsById)
+
+	var created *quiz.Question
+	if formErr == nil {
+		var err error
+		created, err = context.DB.CreateQuestionAudited(question, auditEntry(admin, "create", describeAnswer(question, packsById)))
+		if errors.Is(err, quiz.ErrDuplicate) {
+			formErr = fmt.Errorf("%s is already in the question bank", question.Question)
+		} else if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	search := ""
+	if created != nil {
+		search = created.Question
+		questionsChanged(writer)
+	}
+
+	table, err := context.adminQuestionTable(search, 1)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if formErr != nil {
+		table.Error = formErr.Error()
+	}
+
+	context.renderAdminTable(writer, table)
+}
+
+func (context Context) adminUpdateQuestion(writer http.ResponseWriter, request *http.Request, admin string, existing quiz.Question) {
+	_, packsById, err := context.adminPacks()
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	question, err := questionFromForm(request, existing, packsById)
+	if err != nil {
+		context.renderAdminRow(writer, "adminQuestionEditRow.html", existing, err.Error())
+		return
+	}
+
+	detail := describeQuestionChange(existing, question, packsById)
+	if detail == "" {
+		context.renderAdminRow(writer, "adminQuestionRow.html", existing, "")
+		return
+	}
+
+	if _, err := context.DB.UpdateQuestionAudited(question, auditEntry(admin, "update", detail)); errors.Is(err, quiz.ErrDuplicate) {
+		context.renderAdminRow(writer, "adminQuestionEditRow.html", existing, fmt.Sprintf("%s is already in the question bank", question.Question))
+		return
+	} else if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	questionsChanged(writer)
+
+	context.renderAdminRow(writer, "adminQuestionRow.html", question, "")
+}
+
+func (context Context) adminSetDisabled(writer http.ResponseWriter, admin string, question quiz.Question, disabled bool) {
+	if question.Disabled != disabled {
+		question.Disabled = disabled
+
+		action := "enable"
+		if disabled {
+			action = "disable"
+		}
+
+		if _, err := context.DB.UpdateQuestionAudited(question, auditEntry(admin, action, "")); err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		questionsChanged(writer)
+	}
+
+	context.renderAdminRow(writer, "adminQuestionRow.html", question, "")
+}
+
+func (context Context) adminDeleteQuestion(writer http.ResponseWriter, admin string, question quiz.Question) {
+	_, packsById, err := context.adminPacks()
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := context.DB.DeleteQuestionAudited(question, auditEntry(admin, "delete", describeAnswer(question, packsById))); err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	questionsChanged(writer)
+
+	// An empty body lets htmx swap the row out of the table.
+	writer.WriteHeader(http.StatusOK)
+}
+
+// auditEntry records an admin's change, for the repository to store along
+// with it.
+func auditEntry(admin string, action string, detail string) quiz.AuditEntry {
+	return quiz.AuditEntry{Actor: admin, Action: action, Detail: detail, At: time.Now()}
+}
+
+// questionsChanged lets the recent changes list on the admin page refresh
+// itself.
+func questionsChanged(writer http.ResponseWriter) {
+	writer.Header().Set("HX-Trigger", "questionsChanged")
+}
+
+// adminPacks returns every pack both in order and by id.
+func (context Context) adminPacks() ([]quiz.Pack, map[int64]quiz.Pack, error) {
+	packs, err := context.DB.AllPacks()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	packsById := map[int64]quiz.Pack{}
+	for _, pack := range packs {
+		packsById[pack.Id] = pack
+	}
+	return packs, packsById, nil
+}
+
+// questionFromForm applies the submitted word, answer, source and notes to a
+// copy of question. The answer is an option id, and the question moves to
+// whichever pack that option belongs to.
+func questionFromForm(request *http.Request, question quiz.Question, packs map[int64]quiz.Pack) (quiz.Question, error) {
+	question.Question = strings.TrimSpace(request.PostFormValue("question"))
+	if question.Question == "" {
+		return question, errors.New("word is required")
+	}
+
+	answer, err := parseAnswer(request.PostFormValue("answer"))
+	if err != nil {
+		return question, err
+	}
+
+	pack, ok := packWithOption(packs, answer)
+	if !ok {
+		return question, fmt.Errorf("%w: %d is not an option in any pack", quiz.ErrInvalidAnswer, answer)
+	}
+
+	question.PackId = pack.Id
+	question.Answer = answer
+	question.Source = strings.TrimSpace(request.PostFormValue("source"))
+	question.Notes = strings.TrimSpace(request.PostFormValue("notes"))
+
+	return question, nil
+}
+
+func packWithOption(packs map[int64]quiz.Pack, answer quiz.Answer) (quiz.Pack, bool) {
+	for _, pack := range packs {
+		if pack.CheckAnswer(answer) == nil {
+			return pack, true
+		}
+	}
+	return quiz.Pack{}, false
+}
+
+func describeAnswer(question quiz.Question, packs map[int64]quiz.Pack) string {
+	pack := packs[question.PackId]
+	return fmt.Sprintf("answer %s in %s", pack.Label(question.Answer), pack.Name)
+}
+
+func describeQuestionChange(before quiz.Question, after quiz.Question, packs map[int64]quiz.Pack) string {
+	var changes []string
+
+	if before.Question != after.Question {
+		changes = append(changes, fmt.Sprintf("word %q → %q", before.Question, after.Question))
+	}
+	if before.PackId != after.PackId {
+		changes = append(changes, fmt.Sprintf("pack %s → %s", packs[before.PackId].Name, packs[after.PackId].Name))
+	}
+	if before.Answer != after.Answer || before.PackId != after.PackId {
+		changes = append(changes, fmt.Sprintf("answer %s → %s", packs[before.PackId].Label(before.Answer), packs[after.PackId].Label(after.Answer)))
+	}
+	if before.Source != after.Source {
+		changes = append(changes, fmt.Sprintf("source %q → %q", before.Source, after.Source))
+	}
+	if before.Notes != after.Notes {
+		changes = append(changes, fmt.Sprintf("notes %q → %q", before.Notes, after.Notes))
+	}
+
+	return strings.Join(changes, "; ")
+}
+
+func (context Context) renderAdminTable(writer http.ResponseWriter, table *quiz.AdminQuestionTableStruct) {
+	context.render(writer, "adminQuestionTable.html", table)
+}
+
+func (context Context) renderAdminRow(writer http.ResponseWriter, page string, question quiz.Question, errorMessage string) {
+	packs, packsById, err := context.adminPacks()
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	context.render(writer, page, quiz.AdminQuestionRowStruct{Question: question, Pack: packsById[question.PackId], Packs: packs, Error: errorMessage})
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"io"
+	"me885/fintech-or-furniture/quiz"
+	"me885/fintech-or-furniture/quiz/database"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newAdminRequest(method string, target string, form url.Values) *http.Request {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req := httptest.NewRequest(method, target, body)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("HX-Request", "true")
+	req.SetBasicAuth("alice", "secret")
+
+	return req
+}
+
+func newAdminContext() Context {
+	return Context{DB: database.InitMemoryDatabase(), Admins: ParseAdmins("alice:secret")}
+}
+
+func TestParseAdmins(t *testing.T) {
+	admins := ParseAdmins("alice:secret, bob:hunter2:x,broken,:nobody,")
+
+	if len(admins) != 2 || admins["alice"] != "secret" || admins["bob"] != "hunter2:x" {
+		t.Fatal(admins)
+	}
+}
+
+func TestAdminPage_RequiresCredentials(t *testing.T) {
+	handlerContext := newAdminContext()
+
+	for _, password := range []string{"", "wrong"} {
+		req := httptest.NewRequest("GET", "/admin/", nil)
+		if password != "" {
+			req.SetBasicAuth("alice", password)
+		}
+
+		resp := httptest.NewRecorder()
+		http.HandlerFunc(handlerContext.AdminPage).ServeHTTP(resp, req)
+
+		if resp.Code != 401 || resp.Header().Get("WWW-Authenticate") == "" {
+			t.Fatal(resp.Code, resp.Header())
+		}
+	}
+}
+
+func TestAdminPage(t *testing.T) {
+	handlerContext := newAdminContext()
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.AdminPage).ServeHTTP(resp, newAdminRequest("GET", "/admin/", nil))
+
+	html := resp.Body.String()
+
+	if resp.Code != 200 || !strings.Contains(html, "Question Bank") || !strings.Contains(html, "PAX") || !strings.Contains(html, "Page 1 of 3") {
+		t.Fatal(resp.Code, html)
+	}
+}
+
+func TestAdminQuestions_Search(t *testing.T) {
+	handlerContext := newAdminContext()
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.AdminQuestions).ServeHTTP(resp, newAdminRequest("GET", "/admin/questions/?search=kal", nil))
+
+	html := resp.Body.String()
+
+	if !strings.Contains(html, "KALLAX") || strings.Contains(html, "PAX<") || !strings.Contains(html, "1 questions") {
+		t.Fatal(html)
+	}
+}
+
+func TestAdminQuestions_Create(t *testing.T) {
+	handlerContext := newAdminContext()
+
+	form := url.Values{"question": {"BILLY"}, "answer": {formatAnswer(quiz.Furniture)}, "notes": {"bookcase"}}
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.AdminQuestions).ServeHTTP(resp, newAdminRequest("POST", "/admin/questions/", form))
+
+	if !strings.Contains(resp.Body.String(), "BILLY") {
+		t.Fatal(resp.Body.String())
+	}
+
+	question, err := handlerContext.DB.GetQuestionByText("BILLY")
+	if err != nil || question.Answer != quiz.Furniture || question.Notes != "bookcase" {
+		t.Fatal(question, err)
+	}
+
+	entries, _ := handlerContext.DB.AuditEntries(10)
+	if len(entries) != 1 || entries[0].Actor != "alice" || entries[0].Action != "create" || entries[0].Question != "BILLY" {
+		t.Fatal(entries)
+	}
+}
+
+func TestAdminQuestions_CreateInOtherPack(t *testing.T) {
+	handlerContext := newAdminContext()
+	pack, _ := handlerContext.DB.GetPackBySlug("pokemon-or-pharma")
+
+	form := url.Values{"question": {"PIKACHU"}, "answer": {formatAnswer(pack.Options[0].Id)}}
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.AdminQuestions).ServeHTTP(resp, newAdminRequest("POST", "/admin/questions/", form))
+
+	question, err := handlerContext.DB.GetQuestionByText("PIKACHU")
+	if err != nil || question.PackId != pack.Id || question.Answer != pack.Options[0].Id {
+		t.Fatal(question, err, resp.Body.String())
+	}
+
+	form = url.Values{"question": {"RAICHU"}, "answer": {"99"}}
+
+	resp = httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.AdminQuestions).ServeHTTP(resp, newAdminRequest("POST", "/admin/questions/", form))
+
+	if !strings.Contains(resp.Body.String(), "not an option in any pack") {
+		t.Fatal(resp.Body.String())
+	}
+}
+
+func TestAdminQuestions_CreateDuplicate(t *testing.T) {
+	handlerContext := newAdminContext()
+
+	form := url.Values{"question": {"PAX"}, "answer": {formatAnswer(quiz.Fintech)}}
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.AdminQuestions).ServeHTTP(resp, newAdminRequest("POST", "/admin/questions/", form))
+
+	if !strings.Contains(resp.Body.String(), "PAX is already in the question bank") {
+		t.Fatal(resp.Body.String())
+	}
+
+	entries, _ := handlerContext.DB.AuditEntries(10)
+	if len(entries) != 0 {
+		t.Fatal(entries)
+	}
+}
+
+func TestAdminQuestions_RequiresHtmx(t *testing.T) {
+	handlerContext := newAdminContext()
+
+	req := newAdminRequest("DELETE", "/admin/questions/1/", nil)
+	req.Header.Del("HX-Request")
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.AdminQuestions).ServeHTTP(resp, req)
+
+	if resp.Code != 403 {
+		t.Fatal(resp.Code)
+	}
+
+	if _, err := handlerContext.DB.GetQuestionById(1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdminQuestions_Update(t *testing.T) {
+	handlerContext := newAdminContext()
+
+	form := url.Values{"question": {"PAX"}, "answer": {formatAnswer(quiz.Furniture)}, "source": {"Ikea"}}
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.AdminQuestions).ServeHTTP(resp, newAdminRequest("POST", "/admin/questions/1/", form))
+
+	if !strings.Contains(resp.Body.String(), "Ikea") {
+		t.Fatal(resp.Body.String())
+	}
+
+	entries, _ := handlerContext.DB.AuditEntries(10)
+	if len(entries) != 1 || entries[0].Action != "update" || entries[0].Detail != `source "" → "Ikea"` {
+		t.Fatal(entries)
+	}
+}
+
+func TestAdminQuestions_DisableAndEnable(t *testing.T) {
+	handlerContext := newAdminContext()
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.AdminQuestions).ServeHTTP(resp, newAdminRequest("POST", "/admin/questions/1/disable/", nil))
+
+	if !strings.Contains(resp.Body.String(), "Enable") {
+		t.Fatal(resp.Body.String())
+	}
+
+	question, _ := handlerContext.DB.GetQuestionById(1)
+	if !question.Disabled {
+		t.Fatal(question)
+	}
+
+	resp = httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.AdminQuestions).ServeHTTP(resp, newAdminRequest("POST", "/admin/questions/1/enable/", nil))
+
+	question, _ = handlerContext.DB.GetQuestionById(1)
+	if question.Disabled {
+		t.Fatal(question)
+	}
+
+	entries, _ := handlerContext.DB.AuditEntries(10)
+	if len(entries) != 2 || entries[0].Action != "enable" || entries[1].Action != "disable" {
+		t.Fatal(entries)
+	}
+}
+
+func TestAdminQuestions_Delete(t *testing.T) {
+	handlerContext := newAdminContext()
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.AdminQuestions).ServeHTTP(resp, newAdminRequest("DELETE", "/admin/questions/1/", nil))
+
+	if resp.Code != 200 || resp.Body.Len() != 0 {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	if _, err := handlerContext.DB.GetQuestionById(1); err != quiz.ErrNotExists {
+		t.Fatal(err)
+	}
+
+	resp = httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.AdminQuestions).ServeHTTP(resp, newAdminRequest("DELETE", "/admin/questions/1/", nil))
+
+	if resp.Code != 404 {
+		t.Fatal(resp.Code)
+	}
+}
+
+func TestAdminAudit(t *testing.T) {
+	handlerContext := newAdminContext()
+
+	http.HandlerFunc(handlerContext.AdminQuestions).ServeHTTP(httptest.NewRecorder(), newAdminRequest("POST", "/admin/questions/2/disable/", nil))
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.AdminAudit).ServeHTTP(resp, newAdminRequest("GET", "/admin/audit/", nil))
+
+	html := resp.Body.String()
+
+	if !strings.Contains(html, "alice") || !strings.Contains(html, "disable") || !strings.Contains(html, "YAVRIO") {
+		t.Fatal(html)
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"me885/fintech-or-furniture/quiz"
+	"net/http"
+)
+
+type apiNewGameRequest struct {
+	Name string `json:"name"`
+	Mode string `json:"mode"`
+	Pack string `json:"pack"`
+}
+
+type apiAnswerRequest struct {
+	Answer quiz.Answer `json:"answer"`
+}
+
+type apiAnswerResponse struct {
+	quiz.NextQuestionModalStruct
+	Game quiz.Game `json:"game"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(writer http.ResponseWriter, status int, body any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+
+	json.NewEncoder(writer).Encode(body)
+}
+
+func writeJSONError(writer http.ResponseWriter, status int, err error) {
+	writeJSON(writer, status, apiError{Error: err.Error()})
+}
+
+func allowMethod(writer http.ResponseWriter, request *http.Request, method string) bool {
+	if request.Method != method {
+		writer.Header().Set("Allow", method)
+		writeJSONError(writer, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return false
+	}
+
+	return true
+}
+
+// APINewGame handles POST /api/v1/games/ with a body of {"name": "..."} and
+// optionally "mode" and "pack". The game session cookie is set for later
+// requests, which act on the session's newest game unless they name one of
+// its earlier games with ?game={id}.
+func (context Context) APINewGame(writer http.ResponseWriter, request *http.Request) {
+	if !allowMethod(writer, request, http.MethodPost) {
+		return
+	}
+
+	var body apiNewGameRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writeJSONError(writer, http.StatusBadRequest, errors.New("body should be a JSON object"))
+		return
+	}
+
+	playerName, playerId, err := context.playingAs(request, body.Name)
+	if err != nil {
+		writeJSONError(writer, errorStatus(err), err)
+		return
+	}
+
+	page, err := context.startGame(writer, request, playerName, playerId, body.Mode, body.Pack)
+	if err != nil {
+		writeJSONError(writer, errorStatus(err), err)
+		return
+	}
+
+	writeJSON(writer, http.StatusCreated, page)
+}
+
+// APIQuestion handles GET /api/v1/question/, returning the question awaiting
+// an answer, or serving a new one if the last was already answered.
+func (context Context) APIQuestion(writer http.ResponseWriter, request *http.Request) {
+	if !allowMethod(writer, request, http.MethodGet) {
+		return
+	}
+
+	game, err := getGameIfAuthed(request, context.DB)
+	if err != nil {
+		writeJSONError(writer, http.StatusUnauthorized, err)
+		return
+	}
+
+	page, err := context.currentQuestion(game)
+	if err != nil {
+		writeJSONError(writer, errorStatus(err), err)
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, page)
+}
+
+// APIAnswer handles POST /api/v1/answer/{questionId}/ with a body of
+// {"answer": optionId}, where optionId is one of the ids in the question's
+// pack options.
+func (context Context) APIAnswer(writer http.ResponseWriter, request *http.Request) {
+	if !allowMethod(writer, request, http.MethodPost) {
+		return
+	}
+
+	game, err := getGameIfAuthed(request, context.DB)
+	if err != nil {
+		writeJSONError(writer, http.StatusUnauthorized, err)
+		return
+	}
+
+	questionId, err := parseQuestionId(request.URL.Path)
+	if err != nil {
+		writeJSONError(writer, errorStatus(err), err)
+		return
+	}
+
+	var body apiAnswerRequest
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writeJSONError(writer, http.StatusBadRequest, errors.New("body should be a JSON object"))
+		return
+	}
+
+	result, err := context.submitAnswer(game, questionId, body.Answer)
+	if err != nil {
+		writeJSONError(writer, errorStatus(err), err)
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, apiAnswerResponse{NextQuestionModalStruct: *result, Game: *game})
+}
+
+// APIResult handles GET /api/v1/result/.
+func (context Context) APIResult(writer http.ResponseWriter, request *http.Request) {
+	if !allowMethod(writer, request, http.MethodGet) {
+		return
+	}
+
+	game, err := getGameIfAuthed(request, context.DB)
+	if err != nil {
+		writeJSONError(writer, http.StatusUnauthorized, err)
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, game)
+}
+
+// APILeaderboard handles GET /api/v1/leaderboard/?mode=...&time-select=...
+func (context Context) APILeaderboard(writer http.ResponseWriter, request *http.Request) {
+	if !allowMethod(writer, request, http.MethodGet) {
+		return
+	}
+
+	leaderboard, err := context.leaderboard(request.URL.Query().Get("mode"), request.URL.Query().Get("time-select"))
+	if err != nil {
+		writeJSONError(writer, errorStatus(err), err)
+		return
+	}
+
+	games := leaderboard.Games
+	if games == nil {
+		games = []quiz.Game{}
+	}
+
+	writeJSON(writer, http.StatusOK, games)
+}
+
+// APIDailyLeaderboard handles GET /api/v1/leaderboard/daily/?day=2026-10-18,
+// today's challenge when no day is given.
+func (context Context) APIDailyLeaderboard(writer http.ResponseWriter, request *http.Request) {
+	if !allowMethod(writer, request, http.MethodGet) {
+		return
+	}
+
+	leaderboard, err := context.dailyLeaderboard(request.URL.Query().Get("day"))
+	if err != nil {
+		writeJSONError(writer, errorStatus(err), err)
+		return
+	}
+
+	games := leaderboard.Games
+	if games == nil {
+		games = []quiz.Game{}
+	}
+
+	writeJSON(writer, http.StatusOK, games)
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"me885/fintech-or-furniture/quiz"
+	"me885/fintech-or-furniture/quiz/database"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPINewGame(t *testing.T) {
+	req, err := http.NewRequest("POST", "/api/v1/games/", strings.NewReader(`{"name": "testname"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDb := database.InitMemoryDatabase()
+	handlerContext := Context{DB: testDb}
+
+	handler := http.HandlerFunc(handlerContext.APINewGame)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	if resp.Code != 201 {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	var page quiz.QuestionPageStruct
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+
+	if page.Game.PlayerName != "testname" || page.Question.Question == "" || page.Game.CurrentQuestionId != page.Question.Id {
+		t.Fatal(page)
+	}
+
+	if game := sessionGame(t, testDb, responseSession(t, resp)); game.Id != page.Game.Id {
+		t.Fatal(game, page.Game)
+	}
+}
+
+func TestAPINewGame_MissingName(t *testing.T) {
+	req, err := http.NewRequest("POST", "/api/v1/games/", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDb := database.InitMemoryDatabase()
+	handlerContext := Context{DB: testDb}
+
+	handler := http.HandlerFunc(handlerContext.APINewGame)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	if resp.Code != 400 {
+		t.Fatal(resp.Code)
+	}
+}
+
+func TestAPINewGame_EmptyBank(t *testing.T) {
+	req, err := http.NewRequest("POST", "/api/v1/games/", strings.NewReader(`{"name": "testname"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handlerContext := Context{DB: database.NewMemoryRepository()}
+
+	handler := http.HandlerFunc(handlerContext.APINewGame)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	if resp.Code != http.StatusServiceUnavailable || !strings.Contains(resp.Body.String(), "not enough questions") {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+}
+
+func TestAPIQuestion_WrongMethod(t *testing.T) {
+	req, err := http.NewRequest("POST", "/api/v1/question/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handlerContext := Context{}
+
+	handler := http.HandlerFunc(handlerContext.APIQuestion)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	if resp.Code != 405 {
+		t.Fatal(resp.Code)
+	}
+}
+
+func TestAPIAnswer_Correct(t *testing.T) {
+	req, err := http.NewRequest("POST", "/api/v1/answer/1/", strings.NewReader(`{"answer": 2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDb := database.InitMemoryDatabase()
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+	game.CurrentQuestionId = 1
+	testDb.UpdateGame(game)
+
+	req.AddCookie(sessionCookie(t, testDb, game))
+
+	handlerContext := Context{DB: testDb}
+
+	handler := http.HandlerFunc(handlerContext.APIAnswer)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	var result apiAnswerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Correct || result.Score != 1 || result.Game.QuestionsAnswered != 1 || result.Game.CurrentQuestionId != 0 {
+		t.Fatal(result)
+	}
+}
+
+func TestAPIAnswer_NotCurrentQuestion(t *testing.T) {
+	req, err := http.NewRequest("POST", "/api/v1/answer/2/", strings.NewReader(`{"answer": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDb := database.InitMemoryDatabase()
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+	game.CurrentQuestionId = 1
+	testDb.UpdateGame(game)
+
+	req.AddCookie(sessionCookie(t, testDb, game))
+
+	handlerContext := Context{DB: testDb}
+
+	handler := http.HandlerFunc(handlerContext.APIAnswer)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	if resp.Code != 409 {
+		t.Fatal(resp.Code)
+	}
+
+	if resp.Header().Get("Content-Type") != "application/json" || !strings.Contains(resp.Body.String(), `"error"`) {
+		t.Fatal(resp.Header(), resp.Body.String())
+	}
+}
+
+func TestAPILeaderboard(t *testing.T) {
+	req, err := http.NewRequest("GET", "/api/v1/leaderboard/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDb := database.InitMemoryDatabase()
+
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+	game.QuestionsAnswered = 10
+	game.Score = 7
+	game.InProgress = false
+	game.Completed = time.Now()
+
+	testDb.UpdateGame(game)
+
+	handlerContext := Context{DB: testDb}
+
+	handler := http.HandlerFunc(handlerContext.APILeaderboard)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	var games []quiz.Game
+	if err := json.NewDecoder(resp.Body).Decode(&games); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(games) != 1 || games[0].PlayerName != "testname" || games[0].Score != 7 {
+		t.Fatal(games)
+	}
+}
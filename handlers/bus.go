@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"me885/fintech-or-furniture/quiz"
+	"slices"
+	"sync"
+)
+
+// GameCompleted is published when a game ends, whether it was played to the
+// end, ran out of time or ran out of questions.
+type GameCompleted struct {
+	Game quiz.Game
+}
+
+// Bus passes game events between features inside the server. Subscribers
+// are called in the publisher's goroutine, so they should be quick and hand
+// anything slow off elsewhere.
+type Bus struct {
+	mu            sync.Mutex
+	gameCompleted []func(GameCompleted)
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// OnGameCompleted calls subscriber for every game that completes from now
+// on.
+func (bus *Bus) OnGameCompleted(subscriber func(GameCompleted)) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.gameCompleted = append(bus.gameCompleted, subscriber)
+}
+
+// PublishGameCompleted tells the subscribers a game has completed. Publishing
+// to a nil Bus does nothing.
+func (bus *Bus) PublishGameCompleted(event GameCompleted) {
+	if bus == nil {
+		return
+	}
+
+	bus.mu.Lock()
+	subscribers := slices.Clone(bus.gameCompleted)
+	bus.mu.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(event)
+	}
+}
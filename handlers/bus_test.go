@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"me885/fintech-or-furniture/quiz"
+	"testing"
+)
+
+func TestBus_PublishGameCompleted(t *testing.T) {
+	bus := NewBus()
+
+	var completed []string
+	bus.OnGameCompleted(func(event GameCompleted) { completed = append(completed, "first "+event.Game.PlayerName) })
+	bus.OnGameCompleted(func(event GameCompleted) { completed = append(completed, "second "+event.Game.PlayerName) })
+
+	bus.PublishGameCompleted(GameCompleted{Game: quiz.Game{PlayerName: "bob"}})
+
+	if len(completed) != 2 || completed[0] != "first bob" || completed[1] != "second bob" {
+		t.Fatal(completed)
+	}
+
+	var nilBus *Bus
+	nilBus.PublishGameCompleted(GameCompleted{})
+}
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"me885/fintech-or-furniture/quiz"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// requestError carries the HTTP status a failure should be reported with, so
+// the HTML and JSON handlers can share the same game logic.
+type requestError struct {
+	status int
+	err    error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func errorStatus(err error) int {
+	var requestErr *requestError
+	if errors.As(err, &requestErr) {
+		return requestErr.status
+	}
+
+	return http.StatusInternalServerError
+}
+
+var questionIdRegex = regexp.MustCompile(`answer/([0-9]*)/`)
+
+func parseQuestionId(path string) (int64, error) {
+	match := questionIdRegex.FindStringSubmatch(path)
+	if match == nil {
+		return 0, &requestError{http.StatusBadRequest, errors.New("QuestionId must specified in URL path")}
+	}
+
+	questionId, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, &requestError{http.StatusBadRequest, errors.New("QuestionId must specified in URL path")}
+	}
+
+	return questionId, nil
+}
+
+// parseAnswer reads the id of the answer option a player picked.
+func parseAnswer(value string) (quiz.Answer, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, &requestError{http.StatusBadRequest, fmt.Errorf("%w: should be the id of one of the pack's options", quiz.ErrInvalidAnswer)}
+	}
+
+	return quiz.Answer(id), nil
+}
+
+var errGameFinished = &requestError{http.StatusUnauthorized, errors.New("Game is finished. Connot answer more questions")}
+
+// startGame starts a game for playerName in the request's game session,
+// linked to the registered player with playerId unless it is 0.
+func (context Context) startGame(writer http.ResponseWriter, request *http.Request, playerName string, playerId int64, modeName string, packSlug string) (*quiz.QuestionPageStruct, error) {
+	if playerName == "" {
+		return nil, &requestError{http.StatusBadRequest, errors.New("name is required")}
+	}
+
+	if modeName == "" {
+		modeName = quiz.DefaultMode
+	}
+
+	mode, err := context.modes().Get(modeName)
+	if err != nil {
+		return nil, &requestError{http.StatusBadRequest, err}
+	}
+
+	if packSlug == "" || mode.IsDaily() {
+		// Everyone plays the same daily challenge, so it always uses the
+		// default pack.
+		packSlug = quiz.DefaultPack.Slug
+	}
+
+	pack, err := context.DB.GetPackBySlug(packSlug)
+	if errors.Is(err, quiz.ErrNotExists) {
+		return nil, &requestError{http.StatusBadRequest, fmt.Errorf("unknown pack %q", packSlug)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := context.DB.CountEnabledQuestions(pack.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := quiz.CheckBankSize(mode, available); err != nil {
+		return nil, &requestError{http.StatusServiceUnavailable, err}
+	}
+
+	start := quiz.NewGame{PlayerName: playerName, Mode: mode.Name, PackId: pack.Id, PlayerId: playerId}
+	if mode.IsDaily() {
+		start.ChallengeDay = quiz.ChallengeDay(time.Now())
+	}
+
+	game, err := context.createGame(writer, request, start)
+	if errors.Is(err, quiz.ErrDuplicate) {
+		return nil, &requestError{http.StatusConflict, fmt.Errorf("%s has already played today's challenge", playerName)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	question, err := GetNextQuestion(context.DB, game, context.selectorFor(game))
+	if errors.Is(err, quiz.ErrOutOfQuestions) {
+		// The bank was emptied after it was counted.
+		return nil, &requestError{http.StatusServiceUnavailable, err}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return context.questionPage(game, *pack, question), nil
+}
+
+// createGame creates a game in the request's game session, once the caller
+// has checked that it can be played, and only then renews the session's
+// cookie. A game that cannot start leaves the player's session, and so their
+// earlier games, as they were.
+func (context Context) createGame(writer http.ResponseWriter, request *http.Request, start quiz.NewGame) (*quiz.Game, error) {
+	session, err := context.claimGameSession(request)
+	if err != nil {
+		return nil, err
+	}
+
+	start.SessionId = session.Id
+	game, err := context.DB.CreateGame(start)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := context.renewGameSession(writer, request, session); err != nil {
+		return nil, err
+	}
+
+	return game, nil
+}
+
+// currentQuestion returns errGameFinished once the game is over, including
+// when its time limit has just run out or every question has been asked.
+func (context Context) currentQuestion(game *quiz.Game) (*quiz.QuestionPageStruct, error) {
+	if game.InProgress && quiz.IsOutOfTime(game, context.modes().For(*game), time.Now()) {
+		if err := context.finishGame(game); err != nil {
+			return nil, err
+		}
+	}
+
+	// Games in a room are served their questions by the room, which may be
+	// due to move on without players who have run out of time to answer.
+	if game.InProgress && game.RoomCode != "" && game.CurrentQuestionId == 0 {
+		served, err := context.roomDue(game)
+		if err != nil {
+			return nil, err
+		}
+		game = served
+	}
+
+	if !game.InProgress {
+		return nil, errGameFinished
+	}
+
+	if game.RoomCode != "" && game.CurrentQuestionId == 0 {
+		return nil, errWaitingForRoom
+	}
+
+	question, err := GetNextQuestion(context.DB, game, context.selectorFor(game))
+	if errors.Is(err, quiz.ErrOutOfQuestions) {
+		game.OutOfQuestions = true
+		if err := context.finishGame(game); err != nil {
+			return nil, err
+		}
+		return nil, errGameFinished
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pack, err := context.DB.GetPackById(game.PackId)
+	if err != nil {
+		return nil, err
+	}
+
+	return context.questionPage(game, *pack, question), nil
+}
+
+func (context Context) submitAnswer(game *quiz.Game, questionId int64, answer quiz.Answer) (*quiz.NextQuestionModalStruct, error) {
+	if !game.InProgress {
+		return nil, errGameFinished
+	}
+
+	if err := quiz.CheckAnswerable(game, questionId); err != nil {
+		return nil, &requestError{http.StatusConflict, err}
+	}
+
+	mode := context.modes().For(*game)
+
+	if quiz.IsOutOfTime(game, mode, time.Now()) {
+		if err := context.finishGame(game); err != nil {
+			return nil, err
+		}
+
+		return &quiz.NextQuestionModalStruct{Correct: false, Score: game.Score, OutOfTime: true}, nil
+	}
+
+	question, err := context.DB.GetQuestionById(questionId)
+	if err != nil {
+		return nil, err
+	}
+
+	pack, err := context.DB.GetPackById(game.PackId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	questionOutOfTime := quiz.IsQuestionOutOfTime(game, mode, now)
+	scoreBefore := game.Score
+
+	wasCorrect, err := quiz.HandleAnswer(answer, *pack, *question, game, mode, now)
+	if err != nil {
+		return nil, &requestError{http.StatusBadRequest, err}
+	}
+
+	if err := context.DB.ClaimCurrentQuestion(game.Id, questionId); err != nil {
+		return nil, &requestError{http.StatusConflict, quiz.ErrNoCurrentQuestion}
+	}
+
+	game.CurrentQuestionId = 0
+
+	// Answers after the deadline say nothing about how hard the question is.
+	// The statistics are best effort, so a failure to record them does not
+	// cost the player their answer.
+	if !questionOutOfTime {
+		context.DB.RecordAnswer(questionId, wasCorrect)
+	}
+
+	if quiz.IsGameComplete(game, mode) {
+		context.DB.RemoveGameQuestions(game.Id)
+	}
+
+	game.Completed = time.Now()
+
+	if _, err := context.DB.UpdateGame(game); err != nil {
+		return nil, err
+	}
+
+	if !game.InProgress {
+		context.Bus.PublishGameCompleted(GameCompleted{Game: *game})
+	}
+
+	result := &quiz.NextQuestionModalStruct{Correct: wasCorrect, Score: game.Score, OutOfTime: questionOutOfTime, Points: game.Score - scoreBefore, RoomCode: game.RoomCode}
+
+	if game.RoomCode != "" {
+		if result.WaitingForRoom, err = context.roomAnswered(game.RoomCode); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// questionPage includes how long is left to answer so it can be shown, but
+// the deadline itself is only ever checked against the time recorded in the
+// game when the question was served.
+func (context Context) questionPage(game *quiz.Game, pack quiz.Pack, question *quiz.Question) *quiz.QuestionPageStruct {
+	mode := context.modes().For(*game)
+	secondsLeft := int64(math.Ceil(game.QuestionTimeLeft(mode, time.Now()).Seconds()))
+
+	return &quiz.QuestionPageStruct{Question: *question, Game: *game, Mode: mode, Pack: pack, SecondsLeft: secondsLeft}
+}
+
+// endPage shows the finished game's score as its mode counts it.
+func (context Context) endPage(game *quiz.Game) quiz.EndPageStruct {
+	return quiz.EndPageStruct{Game: *game, Mode: context.modes().For(*game)}
+}
+
+// finishGame ends a game early, such as when its time limit runs out.
+func (context Context) finishGame(game *quiz.Game) error {
+	game.InProgress = false
+	game.CurrentQuestionId = 0
+	game.Completed = time.Now()
+
+	context.DB.RemoveGameQuestions(game.Id)
+
+	if _, err := context.DB.UpdateGame(game); err != nil {
+		return err
+	}
+
+	context.Bus.PublishGameCompleted(GameCompleted{Game: *game})
+	return nil
+}
+
+func (context Context) leaderboard(modeName string, timeSelect string) (*quiz.LeaderboardStruct, error) {
+	if modeName == "" {
+		modeName = quiz.DefaultMode
+	}
+
+	mode, err := context.modes().Get(modeName)
+	if err != nil {
+		return nil, &requestError{http.StatusBadRequest, err}
+	}
+
+	since, err := quiz.LeaderboardSince(timeSelect, time.Now())
+	if err != nil {
+		return nil, &requestError{http.StatusBadRequest, err}
+	}
+
+	var games []quiz.Game
+	if mode.RanksByStreak() {
+		games, err = context.DB.TopTenStreaks(mode.Name, since)
+	} else {
+		games, err = context.DB.TopTenCompletedGames(mode.Name, since)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &quiz.LeaderboardStruct{Games: games, Mode: mode, Modes: context.modes(), TimeSelect: timeSelect}, nil
+}
+
+func (context Context) dailyLeaderboard(day string) (*quiz.LeaderboardStruct, error) {
+	day, err := quiz.ParseChallengeDay(day, time.Now())
+	if err != nil {
+		return nil, &requestError{http.StatusBadRequest, err}
+	}
+
+	games, err := context.DB.TopTenDailyGames(day)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, _ := context.modes().Get(quiz.DailyMode)
+
+	return &quiz.LeaderboardStruct{Games: games, Mode: mode, Modes: context.modes(), Day: day}, nil
+}
@@ -1,49 +1,88 @@
 package handlers
 
 import (
+	"bytes"
 	"errors"
-	"math/rand"
 	"me885/fintech-or-furniture/quiz"
-	"me885/fintech-or-furniture/quiz/database"
 	"net/http"
-	"regexp"
-	"strconv"
-	"text/template"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 type Context struct {
-	DB *database.SQLiteRepository
+	DB quiz.Repository
+	// Admins maps the usernames allowed into /admin/ to their passwords.
+	Admins map[string]string
+	// Selector picks each game's questions, quiz.DefaultSelector when nil.
+	Selector quiz.Selector
+	// Modes are the game modes on offer, quiz.GameModes when nil.
+	Modes quiz.Modes
+	// Hub pushes live updates to players, such as rooms moving on. Without
+	// one, pages only change when players act.
+	Hub *Hub
+	// Bus tells other features about games as they happen.
+	Bus *Bus
+	// Templates renders pages, from the embedded templates when nil.
+	Templates *Renderer
+	// SecureCookies marks session cookies as HTTPS only, for servers behind
+	// a proxy that terminates TLS.
+	SecureCookies bool
+	// GameSessionLifetime and PlayerSessionLifetime are how long game
+	// sessions and logins last, quiz.GameSessionLifetime and
+	// quiz.SessionLifetime when 0.
+	GameSessionLifetime   time.Duration
+	PlayerSessionLifetime time.Duration
 }
 
-func RootPage(writer http.ResponseWriter, request *http.Request) {
-	template := template.Must(template.ParseFiles("./templates/index.html"))
-
-	template.Execute(writer, nil)
+func (context Context) modes() quiz.Modes {
+	if context.Modes != nil {
+		return context.Modes
+	}
+	return quiz.GameModes
 }
 
-func (context Context) NewGame(writer http.ResponseWriter, request *http.Request) {
-
-	game, err := context.DB.CreateGame(request.PostFormValue("name"))
+// selectorFor returns what picks the game's questions: its mode's selector
+// if it has one, otherwise the configured one.
+func (context Context) selectorFor(game *quiz.Game) quiz.Selector {
+	if selector := context.modes().For(*game).Selector; selector != nil {
+		return selector
+	}
+	if context.Selector != nil {
+		return context.Selector
+	}
+	return quiz.DefaultSelector
+}
 
+func (context Context) RootPage(writer http.ResponseWriter, request *http.Request) {
+	packs, err := context.DB.AllPacks()
 	if err != nil {
 		writer.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	question, err := GetNextQuestion(context.DB, game)
+	player, err := context.currentPlayer(request)
 	if err != nil {
-		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
-	cookie := http.Cookie{Name: "sessionId", Value: game.Id.String(), HttpOnly: true, SameSite: http.SameSiteLaxMode, Path: "/"}
+	context.render(writer, "index.html", quiz.IndexPageStruct{Modes: context.modes(), Packs: packs, Player: player})
+}
+
+func (context Context) NewGame(writer http.ResponseWriter, request *http.Request) {
 
-	http.SetCookie(writer, &cookie)
+	playerName, playerId, err := context.playingAs(request, request.PostFormValue("name"))
+	if err != nil {
+		http.Error(writer, err.Error(), errorStatus(err))
+		return
+	}
 
-	template := template.Must(template.ParseFiles("./templates/quizQuestion.html"))
-	template.Execute(writer, quiz.QuestionPageStruct{Question: *question, Game: *game})
+	page, err := context.startGame(writer, request, playerName, playerId, request.PostFormValue("mode"), request.PostFormValue("pack"))
+	if err != nil {
+		http.Error(writer, err.Error(), errorStatus(err))
+		return
+	}
+
+	context.render(writer, "quizQuestion.html", page)
 }
 
 func (context Context) Answer(writer http.ResponseWriter, request *http.Request) {
@@ -55,46 +94,30 @@ func (context Context) Answer(writer http.ResponseWriter, request *http.Request)
 		return
 	}
 
-	if !game.InProgress {
-		http.Error(writer, "Game is finished. Connot answer more questions", http.StatusUnauthorized)
-		return
-	}
-
-	regex := regexp.MustCompile(`answer/([0-9]*)/`)
-	questionId, err := strconv.ParseInt(regex.FindStringSubmatch(request.URL.Path)[1], 10, 64)
+	questionId, err := parseQuestionId(request.URL.Path)
 	if err != nil {
-		http.Error(writer, "QuestionId must specified in URL path", http.StatusBadRequest)
+		http.Error(writer, err.Error(), errorStatus(err))
 		return
 	}
 
-	question, err := context.DB.GetQuestionById(questionId)
+	answer, err := parseAnswer(request.URL.Query().Get("answer"))
 	if err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
+		http.Error(writer, err.Error(), errorStatus(err))
 		return
 	}
 
-	answer := request.URL.Query().Get("answer")
-
-	wasCorrect, err := quiz.HandleAnswer(answer, *question, game)
+	result, err := context.submitAnswer(game, questionId, answer)
 	if err != nil {
-		http.Error(writer, err.Error(), http.StatusBadRequest)
+		http.Error(writer, err.Error(), errorStatus(err))
 		return
 	}
 
-	if !quiz.IsGameComplete(game) {
-		template := template.Must(template.ParseFiles("./templates/nextQuestion.html"))
-		template.Execute(writer, quiz.NextQuestionModalStruct{Correct: wasCorrect, Score: game.Score})
+	if game.InProgress {
+		context.render(writer, "nextQuestion.html", result)
 
 	} else {
-		template := template.Must(template.ParseFiles("./templates/endPage.html"))
-		template.Execute(writer, game)
-
-		context.DB.RemoveGameQuestions(game.Id)
+		context.render(writer, "endPage.html", context.endPage(game))
 	}
-
-	game.Completed = time.Now()
-
-	context.DB.UpdateGame(game)
 }
 
 func (context Context) NextQuestion(writer http.ResponseWriter, request *http.Request) {
@@ -106,90 +129,162 @@ func (context Context) NextQuestion(writer http.ResponseWriter, request *http.Re
 		return
 	}
 
-	if !game.InProgress {
-		http.Error(writer, "Game is finished. Connot answer more questions", http.StatusUnauthorized)
+	page, err := context.currentQuestion(game)
+	if err == errGameFinished {
+		context.render(writer, "endPage.html", context.endPage(game))
+		return
+	}
+	if err == errWaitingForRoom {
+		context.roomWaiting(writer, game)
 		return
 	}
-
-	question, err := GetNextQuestion(context.DB, game)
 	if err != nil {
-		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		http.Error(writer, err.Error(), errorStatus(err))
+		return
 	}
 
-	template := template.Must(template.ParseFiles("./templates/quizQuestion.html"))
-	template.Execute(writer, quiz.QuestionPageStruct{Question: *question, Game: *game})
+	context.render(writer, "quizQuestion.html", page)
 }
 
 func (context Context) Leaderboard(writer http.ResponseWriter, request *http.Request) {
-	time := request.URL.Query().Get("time-select")
-
-	games, err := context.DB.TopTenCompletedGames(time)
+	leaderboard, err := context.leaderboard(request.URL.Query().Get("mode"), request.URL.Query().Get("time-select"))
 	if err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
+		http.Error(writer, err.Error(), errorStatus(err))
 		return
 	}
 
-	template := template.Must(template.ParseFiles("./templates/leaderboard.html", "./templates/leaderboardBody.html"))
-	template.Execute(writer, games)
+	context.render(writer, "leaderboard.html", leaderboard)
 }
 
 func (context Context) LeaderboardTable(writer http.ResponseWriter, request *http.Request) {
-	time := request.URL.Query().Get("time-select")
-
-	games, err := context.DB.TopTenCompletedGames(time)
+	leaderboard, err := context.leaderboard(request.URL.Query().Get("mode"), request.URL.Query().Get("time-select"))
 	if err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
+		http.Error(writer, err.Error(), errorStatus(err))
 		return
 	}
 
-	template := template.Must(template.ParseFiles("./templates/leaderboardTable.html", "./templates/leaderboardBody.html"))
-	template.Execute(writer, games)
+	context.render(writer, "leaderboardTable.html", leaderboard)
 }
 
-func (context Context) EndPage(writer http.ResponseWriter, request *http.Request) {
-	game, err := getGameIfAuthed(request, context.DB)
+// LeaderboardEvents streams the rows of a leaderboard, chosen like
+// LeaderboardTable's or by day like DailyLeaderboard's, every time a game in
+// its mode completes.
+func (context Context) LeaderboardEvents(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+
+	render := func(Event) (*Event, error) {
+		var leaderboard *quiz.LeaderboardStruct
+		var err error
+		if query.Has("day") {
+			leaderboard, err = context.dailyLeaderboard(query.Get("day"))
+		} else {
+			leaderboard, err = context.leaderboard(query.Get("mode"), query.Get("time-select"))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var rows bytes.Buffer
+		if err := context.executeTemplate(&rows, "leaderboardBody.html", "rows", leaderboard); err != nil {
+			return nil, err
+		}
+
+		return &Event{Name: "leaderboard", Data: rows.String()}, nil
+	}
 
+	// Render once up front to check the query, and to catch up with games
+	// completed since the page was loaded.
+	first, err := render(Event{})
 	if err != nil {
-		http.Error(writer, err.Error(), http.StatusUnauthorized)
+		http.Error(writer, err.Error(), errorStatus(err))
 		return
 	}
 
-	template := template.Must(template.ParseFiles("./templates/endPage.html"))
-	template.Execute(writer, game)
+	mode := query.Get("mode")
+	if query.Has("day") {
+		mode = quiz.DailyMode
+	} else if mode == "" {
+		mode = quiz.DefaultMode
+	}
+
+	context.Hub.serveEvents(writer, request, leaderboardTopic(mode), first, render)
 }
 
-func getGameIfAuthed(request *http.Request, db *database.SQLiteRepository) (*quiz.Game, error) {
-	cookie, err := request.Cookie("sessionId")
-	if err != nil {
-		return nil, errors.New("sessionId cookie required")
-	}
+func leaderboardTopic(mode string) string {
+	return "leaderboard:" + mode
+}
+
+// PushLeaderboard tells the open leaderboards for the game's mode to
+// refresh. Subscribe it to the Bus's completed games.
+func (context Context) PushLeaderboard(event GameCompleted) {
+	context.Hub.Publish(leaderboardTopic(event.Game.Mode), Event{Name: "leaderboard"})
+}
 
-	gameId, err := uuid.Parse(cookie.Value)
+// DailyLeaderboard shows the best games of a day's challenge, today's unless
+// a day is given.
+func (context Context) DailyLeaderboard(writer http.ResponseWriter, request *http.Request) {
+	leaderboard, err := context.dailyLeaderboard(request.URL.Query().Get("day"))
 	if err != nil {
-		return nil, errors.New("sessionId should be valid uuid")
+		http.Error(writer, err.Error(), errorStatus(err))
+		return
 	}
 
-	game, err := db.GetGameById(gameId)
+	context.render(writer, "dailyLeaderboard.html", leaderboard)
+}
+
+func (context Context) EndPage(writer http.ResponseWriter, request *http.Request) {
+	game, err := getGameIfAuthed(request, context.DB)
+
 	if err != nil {
-		return nil, errors.New("sessionId not found")
+		http.Error(writer, err.Error(), http.StatusUnauthorized)
+		return
 	}
 
-	return game, nil
+	context.render(writer, "endPage.html", context.endPage(game))
 }
 
-func GetNextQuestion(db *database.SQLiteRepository, game *quiz.Game) (*quiz.Question, error) {
+// GetNextQuestion returns the question awaiting an answer, or has selector
+// pick a new one from those the game has not been asked.
+func GetNextQuestion(db quiz.Repository, game *quiz.Game, selector quiz.Selector) (*quiz.Question, error) {
+
+	if game.CurrentQuestionId != 0 {
+		question, err := db.GetQuestionById(game.CurrentQuestionId)
+		if err == nil {
+			return question, nil
+		}
+
+		// The question was deleted from the bank after it was served, so
+		// serve another instead.
+		if !errors.Is(err, quiz.ErrNotExists) {
+			return nil, errors.New("could not access db")
+		}
+
+		game.CurrentQuestionId = 0
+	}
 
 	questionList, err := db.GetUnansweredQuestions(game.Id)
 	if err != nil {
 		return nil, errors.New("could not access db")
 	}
 
-	question := questionList[rand.Intn(len(questionList))]
+	if len(questionList) == 0 {
+		return nil, quiz.ErrOutOfQuestions
+	}
+
+	question := selector.Select(questionList, *game, game.QuestionRand())
 
 	err = db.AddGameQuestion(game.Id, question.Id)
 	if err != nil {
 		return nil, errors.New("could not access db")
 	}
 
-	return &question, err
+	game.CurrentQuestionId = question.Id
+	game.QuestionServed = time.Now().UTC()
+
+	_, err = db.UpdateGame(game)
+	if err != nil {
+		return nil, errors.New("could not access db")
+	}
+
+	return &question, nil
 }
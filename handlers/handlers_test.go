@@ -1,19 +1,46 @@
 package handlers
 
 import (
+	"bufio"
+	"context"
 	"io"
+	"me885/fintech-or-furniture/quiz"
 	"me885/fintech-or-furniture/quiz/database"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 )
 
+// formRequest sends a form to handler with the session cookie, such as a
+// game or login session, or as a newcomer when it is nil.
+func formRequest(handler http.HandlerFunc, method string, target string, form url.Values, session *http.Cookie) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if session != nil {
+		req.AddCookie(session)
+	}
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	return resp
+}
+
+// formatAnswer is how an answer option id is submitted.
+func formatAnswer(answer quiz.Answer) string {
+	return strconv.FormatInt(int64(answer), 10)
+}
+
 func TestRootPage(t *testing.T) {
-	handler := http.HandlerFunc(RootPage)
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+
+	handler := http.HandlerFunc(handlerContext.RootPage)
 
 	req, err := http.NewRequest("GET", "/", nil)
 	if err != nil {
@@ -29,7 +56,7 @@ func TestRootPage(t *testing.T) {
 
 	html := string(body)
 
-	if !strings.Contains(html, "Fintech or Furniture") {
+	if !strings.Contains(html, "Fintech or Furniture") || !strings.Contains(html, "pokemon-or-pharma") {
 		t.Fatal(html)
 	}
 }
@@ -71,10 +98,128 @@ func TestNewGame(t *testing.T) {
 	}
 }
 
+func TestNewGame_UnknownMode(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+
+	resp := formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"testname"}, "mode": {"nonsense"}}, nil)
+
+	if resp.Code != http.StatusBadRequest {
+		t.Fatal(resp.Code)
+	}
+}
+
+func TestNewGame_SmallBank(t *testing.T) {
+	testDb := database.NewMemoryRepository()
+	for _, word := range []string{"PAX", "ZYNGA", "KLARNA", "BILLY", "MONZO"} {
+		testDb.CreateQuestion(quiz.Question{Question: word, Answer: quiz.Fintech})
+	}
+
+	handlerContext := Context{DB: testDb}
+
+	for mode, status := range map[string]int{"quick": http.StatusOK, "classic": http.StatusServiceUnavailable, "survival": http.StatusOK} {
+		resp := formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"testname"}, "mode": {mode}}, nil)
+
+		if resp.Code != status {
+			t.Fatal(mode, resp.Code, resp.Body.String())
+		}
+	}
+}
+
+func TestNewGame_Pack(t *testing.T) {
+	testDb := database.InitMemoryDatabase()
+	handlerContext := Context{DB: testDb}
+
+	resp := formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"testname"}, "mode": {"quick"}, "pack": {"pokemon-or-pharma"}}, nil)
+
+	html := resp.Body.String()
+
+	pack, _ := testDb.GetPackBySlug("pokemon-or-pharma")
+	if !strings.Contains(html, "Is it a Pokémon or Pharma?") || !strings.Contains(html, "answer="+formatAnswer(pack.Options[1].Id)) {
+		t.Fatal(html)
+	}
+
+	games, _ := testDb.AllGames()
+	question, _ := testDb.GetQuestionById(games[0].CurrentQuestionId)
+	if games[0].PackId != pack.Id || question.PackId != pack.Id {
+		t.Fatal(games[0], question)
+	}
+}
+
+func TestNewGame_UnknownPack(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+
+	resp := formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"testname"}, "pack": {"nonsense"}}, nil)
+
+	if resp.Code != http.StatusBadRequest {
+		t.Fatal(resp.Code)
+	}
+}
+
+func TestNewGame_ThreeWayPack(t *testing.T) {
+	testDb := database.InitMemoryDatabase()
+	pack, _ := testDb.CreatePack(quiz.Pack{Slug: "fintech-furniture-or-pharma", Name: "Fintech, Furniture or Pharma", Options: []quiz.AnswerOption{{Label: "Fintech"}, {Label: "Furniture"}, {Label: "Pharma"}}})
+	for i := 0; i < 10; i++ {
+		testDb.CreateQuestion(quiz.Question{Question: "WORD" + strconv.Itoa(i), Answer: pack.Options[i%3].Id, PackId: pack.Id})
+	}
+
+	handlerContext := Context{DB: testDb}
+
+	resp := formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"testname"}, "pack": {pack.Slug}}, nil)
+
+	html := resp.Body.String()
+
+	if !strings.Contains(html, "Is it a Fintech, Furniture or Pharma?") {
+		t.Fatal(html)
+	}
+
+	for _, option := range pack.Options {
+		if !strings.Contains(html, "answer="+formatAnswer(option.Id)) {
+			t.Fatal(option, html)
+		}
+	}
+}
+
+func TestAnswer_PackOptions(t *testing.T) {
+	testDb := database.InitMemoryDatabase()
+	pack, _ := testDb.GetPackBySlug("pokemon-or-pharma")
+	xatu, _ := testDb.GetQuestionByText("XATU")
+
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: pack.Id})
+	game.CurrentQuestionId = xatu.Id
+	testDb.UpdateGame(game)
+
+	handlerContext := Context{DB: testDb}
+
+	// Fintech belongs to another pack, so is not an answer here.
+	for _, attempt := range []struct {
+		answer quiz.Answer
+		status int
+	}{{quiz.Fintech, http.StatusBadRequest}, {pack.Options[0].Id, http.StatusOK}} {
+		answer, status := attempt.answer, attempt.status
+		req, err := http.NewRequest("POST", "/answer/"+strconv.FormatInt(xatu.Id, 10)+"/?answer="+formatAnswer(answer), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.AddCookie(sessionCookie(t, testDb, game))
+
+		resp := httptest.NewRecorder()
+		http.HandlerFunc(handlerContext.Answer).ServeHTTP(resp, req)
+
+		if resp.Code != status {
+			t.Fatal(answer, resp.Code, resp.Body.String())
+		}
+	}
+
+	answered, _ := testDb.GetGameById(game.Id)
+	if answered.Score != 1 {
+		t.Fatal(answered)
+	}
+}
+
 func TestAnswer_NoCookie(t *testing.T) {
 	os.Remove("test.db")
 
-	req, err := http.NewRequest("POST", "/answer/1/?answer=Fintech", nil)
+	req, err := http.NewRequest("POST", "/answer/1/?answer="+formatAnswer(quiz.Fintech), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -102,9 +247,12 @@ func TestAnswer_InvalidAnswer(t *testing.T) {
 	}
 
 	testDb := database.InitDatabase("test.db")
-	game, _ := testDb.CreateGame("testname")
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+	game.CurrentQuestionId = 1
+	testDb.UpdateGame(game)
 
-	req.AddCookie(&http.Cookie{Name: "sessionId", Value: game.Id.String()})
+	req.AddCookie(sessionCookie(t, testDb, game))
 
 	handlerContext := Context{DB: testDb}
 
@@ -120,7 +268,7 @@ func TestAnswer_InvalidAnswer(t *testing.T) {
 func TestAnswer_Correct(t *testing.T) {
 	os.Remove("test.db")
 
-	answer := "Furniture"
+	answer := formatAnswer(quiz.Furniture)
 
 	req, err := http.NewRequest("POST", "/answer/1/?answer="+answer, nil)
 	if err != nil {
@@ -128,9 +276,12 @@ func TestAnswer_Correct(t *testing.T) {
 	}
 
 	testDb := database.InitDatabase("test.db")
-	game, _ := testDb.CreateGame("testname")
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
 
-	req.AddCookie(&http.Cookie{Name: "sessionId", Value: game.Id.String()})
+	game.CurrentQuestionId = 1
+	testDb.UpdateGame(game)
+
+	req.AddCookie(sessionCookie(t, testDb, game))
 
 	handlerContext := Context{DB: testDb}
 
@@ -153,12 +304,17 @@ func TestAnswer_Correct(t *testing.T) {
 	if !strings.Contains(html, "Your current score is: 1") {
 		t.Fatal(html)
 	}
+
+	question, _ := testDb.GetQuestionById(1)
+	if question.TimesServed != 1 || question.TimesCorrect != 1 {
+		t.Fatal(question)
+	}
 }
 
 func TestAnswer_Incorrect(t *testing.T) {
 	os.Remove("test.db")
 
-	answer := "Fintech"
+	answer := formatAnswer(quiz.Fintech)
 
 	req, err := http.NewRequest("POST", "/answer/1/?answer="+answer, nil)
 	if err != nil {
@@ -166,9 +322,12 @@ func TestAnswer_Incorrect(t *testing.T) {
 	}
 
 	testDb := database.InitDatabase("test.db")
-	game, _ := testDb.CreateGame("testname")
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+	game.CurrentQuestionId = 1
+	testDb.UpdateGame(game)
 
-	req.AddCookie(&http.Cookie{Name: "sessionId", Value: game.Id.String()})
+	req.AddCookie(sessionCookie(t, testDb, game))
 
 	handlerContext := Context{DB: testDb}
 
@@ -196,7 +355,7 @@ func TestAnswer_Incorrect(t *testing.T) {
 func TestAnswer_LastQuestion(t *testing.T) {
 	os.Remove("test.db")
 
-	answer := "Fintech"
+	answer := formatAnswer(quiz.Fintech)
 
 	req, err := http.NewRequest("POST", "/answer/1/?answer="+answer, nil)
 	if err != nil {
@@ -204,16 +363,20 @@ func TestAnswer_LastQuestion(t *testing.T) {
 	}
 
 	testDb := database.InitDatabase("test.db")
-	game, _ := testDb.CreateGame("testname")
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
 
 	game.QuestionsAnswered = 9
 	game.Score = 8
+	game.CurrentQuestionId = 1
 
 	testDb.UpdateGame(game)
 
-	req.AddCookie(&http.Cookie{Name: "sessionId", Value: game.Id.String()})
+	req.AddCookie(sessionCookie(t, testDb, game))
 
-	handlerContext := Context{DB: testDb}
+	handlerContext := Context{DB: testDb, Bus: NewBus()}
+
+	var completed []quiz.Game
+	handlerContext.Bus.OnGameCompleted(func(event GameCompleted) { completed = append(completed, event.Game) })
 
 	handler := http.HandlerFunc(handlerContext.Answer)
 
@@ -234,6 +397,205 @@ func TestAnswer_LastQuestion(t *testing.T) {
 	if !strings.Contains(html, "8/10") {
 		t.Fatal(html)
 	}
+
+	if len(completed) != 1 || completed[0].Id != game.Id || completed[0].InProgress {
+		t.Fatal(completed)
+	}
+}
+
+func TestAnswer_ConfiguredQuestionCount(t *testing.T) {
+	modes, err := quiz.GameModes.WithQuestionCounts(map[string]int64{quiz.DefaultMode: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDb := database.InitMemoryDatabase()
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+	game.QuestionsAnswered = 2
+	game.Score = 2
+	game.CurrentQuestionId = 1
+
+	testDb.UpdateGame(game)
+
+	req, err := http.NewRequest("POST", "/answer/1/?answer="+formatAnswer(quiz.Fintech), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(sessionCookie(t, testDb, game))
+
+	handlerContext := Context{DB: testDb, Modes: modes}
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.Answer).ServeHTTP(resp, req)
+
+	if html := resp.Body.String(); !strings.Contains(html, "You achieved the score of:") || !strings.Contains(html, "2/3") {
+		t.Fatal(html)
+	}
+
+	if quiz.GameModes.For(*game).QuestionCount != 10 {
+		t.Fatal("configuring a server changed the default modes")
+	}
+}
+
+func TestAnswer_OutOfTime(t *testing.T) {
+	os.Remove("test.db")
+
+	req, err := http.NewRequest("POST", "/answer/1/?answer="+formatAnswer(quiz.Fintech), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDb := database.InitDatabase("test.db")
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: "blitz", PackId: quiz.DefaultPackId})
+
+	game.Created = time.Now().Add(-2 * time.Minute)
+	game.QuestionsAnswered = 3
+	game.Score = 2
+	game.CurrentQuestionId = 1
+
+	testDb.UpdateGame(game)
+
+	req.AddCookie(sessionCookie(t, testDb, game))
+
+	handlerContext := Context{DB: testDb}
+
+	handler := http.HandlerFunc(handlerContext.Answer)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := string(body)
+
+	if !strings.Contains(html, "2/3") {
+		t.Fatal(html)
+	}
+
+	finished, _ := testDb.GetGameById(game.Id)
+	if finished.InProgress || finished.QuestionsAnswered != 3 {
+		t.Fatal(finished)
+	}
+}
+
+func TestAnswer_AfterQuestionDeadline(t *testing.T) {
+	os.Remove("test.db")
+
+	req, err := http.NewRequest("POST", "/answer/1/?answer="+formatAnswer(quiz.Furniture), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDb := database.InitDatabase("test.db")
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: "timed", PackId: quiz.DefaultPackId})
+
+	game.CurrentQuestionId = 1
+	game.QuestionServed = time.Now().Add(-time.Minute)
+
+	testDb.UpdateGame(game)
+
+	req.AddCookie(sessionCookie(t, testDb, game))
+
+	handlerContext := Context{DB: testDb}
+
+	handler := http.HandlerFunc(handlerContext.Answer)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := string(body)
+
+	if !strings.Contains(html, "Out of time!") || !strings.Contains(html, "Your current score is: 0") {
+		t.Fatal(html)
+	}
+
+	answered, _ := testDb.GetGameById(game.Id)
+	if answered.Score != 0 || answered.Mistakes != 1 || !answered.InProgress {
+		t.Fatal(answered)
+	}
+
+	question, _ := testDb.GetQuestionById(1)
+	if question.TimesServed != 0 {
+		t.Fatal(question)
+	}
+}
+
+func TestAnswer_NotCurrentQuestion(t *testing.T) {
+	os.Remove("test.db")
+
+	req, err := http.NewRequest("POST", "/answer/2/?answer="+formatAnswer(quiz.Fintech), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDb := database.InitDatabase("test.db")
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+	game.CurrentQuestionId = 1
+	testDb.UpdateGame(game)
+
+	req.AddCookie(sessionCookie(t, testDb, game))
+
+	handlerContext := Context{DB: testDb}
+
+	handler := http.HandlerFunc(handlerContext.Answer)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	if resp.Code != 409 {
+		t.Fatal(resp.Code)
+	}
+
+	game, _ = testDb.GetGameById(game.Id)
+	if game.QuestionsAnswered != 0 || game.CurrentQuestionId != 1 {
+		t.Fatal(game)
+	}
+}
+
+func TestAnswer_Repeated(t *testing.T) {
+	os.Remove("test.db")
+
+	testDb := database.InitDatabase("test.db")
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+	game.CurrentQuestionId = 1
+	testDb.UpdateGame(game)
+
+	handlerContext := Context{DB: testDb}
+
+	handler := http.HandlerFunc(handlerContext.Answer)
+	cookie := sessionCookie(t, testDb, game)
+
+	codes := []int{}
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("POST", "/answer/1/?answer="+formatAnswer(quiz.Furniture), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.AddCookie(cookie)
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		codes = append(codes, resp.Code)
+	}
+
+	if codes[0] != 200 || codes[1] != 409 {
+		t.Fatal(codes)
+	}
+
+	game, _ = testDb.GetGameById(game.Id)
+	if game.QuestionsAnswered != 1 || game.Score != 1 {
+		t.Fatal(game)
+	}
 }
 
 func TestNextQuestion_NoCookie(t *testing.T) {
@@ -266,12 +628,12 @@ func TestNextQuestion(t *testing.T) {
 	}
 
 	testDb := database.InitDatabase("test.db")
-	game, _ := testDb.CreateGame("testname")
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
 
 	game.QuestionsAnswered = 1
 	testDb.UpdateGame(game)
 
-	req.AddCookie(&http.Cookie{Name: "sessionId", Value: game.Id.String()})
+	req.AddCookie(sessionCookie(t, testDb, game))
 
 	handlerContext := Context{DB: testDb}
 
@@ -292,6 +654,96 @@ func TestNextQuestion(t *testing.T) {
 	}
 }
 
+func TestNextQuestion_Selector(t *testing.T) {
+	testDb := database.InitMemoryDatabase()
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+	kallax, _ := testDb.GetQuestionByText("KALLAX")
+
+	req, err := http.NewRequest("GET", "/next-question/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(sessionCookie(t, testDb, game))
+
+	handlerContext := Context{DB: testDb, Selector: quiz.SequenceSelector{QuestionIds: []int64{kallax.Id}}}
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.NextQuestion).ServeHTTP(resp, req)
+
+	if !strings.Contains(resp.Body.String(), "'KALLAX'") {
+		t.Fatal(resp.Body.String())
+	}
+}
+
+// playQuestions serves and answers count questions in game, returning the ids
+// of the questions asked in order.
+func playQuestions(t *testing.T, db quiz.Repository, game *quiz.Game, count int) []int64 {
+	var asked []int64
+	for i := 0; i < count; i++ {
+		question, err := GetNextQuestion(db, game, quiz.UniformSelector{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		asked = append(asked, question.Id)
+
+		db.ClaimCurrentQuestion(game.Id, question.Id)
+		game.CurrentQuestionId = 0
+		game.QuestionsAnswered++
+		db.UpdateGame(game)
+	}
+	return asked
+}
+
+func TestGetNextQuestion_ReplaysWithSameSeed(t *testing.T) {
+	var plays [][]int64
+	for _, seed := range []int64{42, 42, 43} {
+		testDb := database.InitMemoryDatabase()
+		game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+		game.Seed = seed
+		testDb.UpdateGame(game)
+
+		plays = append(plays, playQuestions(t, testDb, game, 10))
+	}
+
+	if !slices.Equal(plays[0], plays[1]) || slices.Equal(plays[0], plays[2]) {
+		t.Fatal(plays)
+	}
+}
+
+func TestNextQuestion_ServesCurrentQuestionAgain(t *testing.T) {
+	os.Remove("test.db")
+
+	testDb := database.InitDatabase("test.db")
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+	game.CurrentQuestionId = 3
+	testDb.UpdateGame(game)
+
+	req, err := http.NewRequest("GET", "/next-question/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(sessionCookie(t, testDb, game))
+
+	handlerContext := Context{DB: testDb}
+
+	handler := http.HandlerFunc(handlerContext.NextQuestion)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := string(body)
+
+	if !strings.Contains(html, "'YPPERLIG'") {
+		t.Fatal(html)
+	}
+}
+
 func TestLeaderboard(t *testing.T) {
 	os.Remove("test.db")
 
@@ -302,8 +754,8 @@ func TestLeaderboard(t *testing.T) {
 
 	testDb := database.InitDatabase("test.db")
 
-	game1, _ := testDb.CreateGame("testname1")
-	game2, _ := testDb.CreateGame("testname2")
+	game1, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname1", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+	game2, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname2", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
 
 	game1.QuestionsAnswered = 10
 	game2.QuestionsAnswered = 10
@@ -351,7 +803,7 @@ func TestResult(t *testing.T) {
 	}
 
 	testDb := database.InitDatabase("test.db")
-	game, _ := testDb.CreateGame("testname")
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
 
 	game.QuestionsAnswered = 10
 	game.Score = 8
@@ -359,7 +811,7 @@ func TestResult(t *testing.T) {
 
 	testDb.UpdateGame(game)
 
-	req.AddCookie(&http.Cookie{Name: "sessionId", Value: game.Id.String()})
+	req.AddCookie(sessionCookie(t, testDb, game))
 
 	handlerContext := Context{DB: testDb}
 
@@ -383,3 +835,274 @@ func TestResult(t *testing.T) {
 		t.Fatal(html)
 	}
 }
+
+func TestLeaderboard_PartitionedByMode(t *testing.T) {
+	req, err := http.NewRequest("GET", "/leaderboard-content/?time-select=start of day&mode=quick", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDb := database.InitMemoryDatabase()
+
+	classic, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "classicplayer", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+	quick, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "quickplayer", Mode: "quick", PackId: quiz.DefaultPackId})
+
+	for _, game := range []*quiz.Game{classic, quick} {
+		game.QuestionsAnswered = 5
+		game.Score = 4
+		game.InProgress = false
+		game.Completed = time.Now()
+		testDb.UpdateGame(game)
+	}
+
+	handlerContext := Context{DB: testDb}
+
+	handler := http.HandlerFunc(handlerContext.LeaderboardTable)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := string(body)
+
+	if !strings.Contains(html, "quickplayer") || !strings.Contains(html, "4/5") {
+		t.Fatal(html)
+	}
+	if strings.Contains(html, "classicplayer") {
+		t.Fatal(html)
+	}
+}
+
+func TestNextQuestion_BankExhausted(t *testing.T) {
+	req, err := http.NewRequest("GET", "/next-question/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDb := database.InitMemoryDatabase()
+	questions, _ := testDb.AllQuestions()
+
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: "survival", PackId: quiz.DefaultPackId})
+	for _, question := range questions {
+		testDb.AddGameQuestion(game.Id, question.Id)
+	}
+	game.QuestionsAnswered, _ = testDb.CountEnabledQuestions(quiz.DefaultPackId)
+	game.Score = game.QuestionsAnswered
+	game.BestStreak = game.QuestionsAnswered
+	testDb.UpdateGame(game)
+
+	req.AddCookie(sessionCookie(t, testDb, game))
+
+	handlerContext := Context{DB: testDb}
+
+	handler := http.HandlerFunc(handlerContext.NextQuestion)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := string(body)
+
+	if !strings.Contains(html, "33 in a row") || !strings.Contains(html, "every question we have") {
+		t.Fatal(html)
+	}
+
+	finished, _ := testDb.GetGameById(game.Id)
+	if finished.InProgress || !finished.OutOfQuestions {
+		t.Fatal(finished)
+	}
+}
+
+func TestNextQuestion_QuestionsDisabledMidGame(t *testing.T) {
+	req, err := http.NewRequest("GET", "/next-question/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testDb := database.NewMemoryRepository()
+	pax, _ := testDb.CreateQuestion(quiz.Question{Question: "PAX", Answer: quiz.Furniture})
+	zynga, _ := testDb.CreateQuestion(quiz.Question{Question: "ZYNGA", Answer: quiz.Fintech})
+
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "testname", Mode: "survival", PackId: quiz.DefaultPackId})
+	testDb.AddGameQuestion(game.Id, pax.Id)
+	game.QuestionsAnswered = 1
+	game.Score = 1
+	testDb.UpdateGame(game)
+
+	zynga.Disabled = true
+	testDb.UpdateQuestion(*zynga)
+
+	req.AddCookie(sessionCookie(t, testDb, game))
+
+	handlerContext := Context{DB: testDb}
+
+	handler := http.HandlerFunc(handlerContext.NextQuestion)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), "The End") {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+}
+
+func startDailyGame(handlerContext Context, name string) *httptest.ResponseRecorder {
+	formdata := url.Values{}
+	formdata.Set("name", name)
+	formdata.Set("mode", quiz.DailyMode)
+	formdata.Set("pack", "pokemon-or-pharma")
+
+	req, _ := http.NewRequest("POST", "/new-game/", strings.NewReader(formdata.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.NewGame).ServeHTTP(resp, req)
+
+	return resp
+}
+
+func TestNewGame_DailySameQuestionsForEveryone(t *testing.T) {
+	testDb := database.InitMemoryDatabase()
+	handlerContext := Context{DB: testDb}
+
+	for _, name := range []string{"alice", "bob"} {
+		if resp := startDailyGame(handlerContext, name); resp.Code != http.StatusOK {
+			t.Fatal(resp.Code, resp.Body.String())
+		}
+	}
+
+	games, _ := testDb.AllGames()
+	if len(games) != 2 {
+		t.Fatal(games)
+	}
+
+	var plays [][]int64
+	for i := range games {
+		game := &games[i]
+		if game.PackId != quiz.DefaultPackId || game.ChallengeDay != quiz.ChallengeDay(time.Now()) {
+			t.Fatal(game)
+		}
+		plays = append(plays, append([]int64{game.CurrentQuestionId}, playQuestions(t, testDb, game, 9)...))
+	}
+
+	if !slices.Equal(plays[0], plays[1]) {
+		t.Fatal(plays)
+	}
+}
+
+func TestNewGame_DailyOncePerName(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+
+	if resp := startDailyGame(handlerContext, "bob"); resp.Code != http.StatusOK {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	resp := startDailyGame(handlerContext, "Bob")
+	if resp.Code != http.StatusConflict || !strings.Contains(resp.Body.String(), "already played today's challenge") {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+}
+
+func TestDailyLeaderboard(t *testing.T) {
+	testDb := database.InitMemoryDatabase()
+
+	today := quiz.ChallengeDay(time.Now())
+	for _, name := range []string{"today", "yesterday"} {
+		day := today
+		if name == "yesterday" {
+			day = quiz.ChallengeDay(time.Now().AddDate(0, 0, -1))
+		}
+
+		game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: name, Mode: quiz.DailyMode, PackId: quiz.DefaultPackId, ChallengeDay: day})
+		game.QuestionsAnswered = 10
+		game.Score = 5
+		game.InProgress = false
+		game.Completed = time.Now()
+		testDb.UpdateGame(game)
+	}
+
+	handlerContext := Context{DB: testDb}
+
+	req, err := http.NewRequest("GET", "/leaderboard/daily/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.DailyLeaderboard).ServeHTTP(resp, req)
+
+	html := resp.Body.String()
+	if !strings.Contains(html, "Daily Challenge") || !strings.Contains(html, today) || !strings.Contains(html, ">today<") || strings.Contains(html, ">yesterday<") {
+		t.Fatal(html)
+	}
+
+	req, _ = http.NewRequest("GET", "/leaderboard/daily/?day=soon", nil)
+	resp = httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.DailyLeaderboard).ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusBadRequest {
+		t.Fatal(resp.Code)
+	}
+}
+
+func TestLeaderboardEvents(t *testing.T) {
+	testDb := database.InitMemoryDatabase()
+
+	handlerContext := Context{DB: testDb, Hub: NewHub(), Bus: NewBus()}
+	handlerContext.Bus.OnGameCompleted(handlerContext.PushLeaderboard)
+
+	server := httptest.NewServer(http.HandlerFunc(handlerContext.LeaderboardEvents))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/leaderboard/events/?mode=quick&time-select="+url.QueryEscape("start of day"), nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	events := bufio.NewScanner(resp.Body)
+	nextEvent := func() string {
+		var event strings.Builder
+		for events.Scan() && events.Text() != "" {
+			event.WriteString(events.Text() + "\n")
+		}
+		return event.String()
+	}
+
+	if event := nextEvent(); !strings.HasPrefix(event, "event: leaderboard\n") || strings.Contains(event, "<td>") {
+		t.Fatal(event)
+	}
+
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "speedy", Mode: "quick", PackId: quiz.DefaultPackId})
+	game.QuestionsAnswered = 5
+	game.Score = 4
+	handlerContext.finishGame(game)
+
+	if event := nextEvent(); !strings.Contains(event, "<td>speedy</td>") || !strings.Contains(event, "4/5") {
+		t.Fatal(event)
+	}
+}
+
+func TestLeaderboardEvents_BadQuery(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase(), Hub: NewHub()}
+
+	req, _ := http.NewRequest("GET", "/leaderboard/events/?time-select=soon", nil)
+	resp := httptest.NewRecorder()
+	http.HandlerFunc(handlerContext.LeaderboardEvents).ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusBadRequest {
+		t.Fatal(resp.Code)
+	}
+}
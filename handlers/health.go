@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"me885/fintech-or-furniture/quiz"
+	"net/http"
+	"time"
+)
+
+// readyTimeout is how long Ready waits for the database to answer.
+const readyTimeout = 2 * time.Second
+
+// Healthy answers /healthz, which succeeds whenever the server is up to
+// answer it, for restarting servers that have hung.
+func (context Context) Healthy(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Cache-Control", "no-store")
+	fmt.Fprintln(writer, "ok")
+}
+
+// Ready answers /readyz, which only succeeds while the database can be
+// reached, for keeping traffic away from servers that can't play games.
+func (context Context) Ready(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Cache-Control", "no-store")
+
+	if err := pingDatabase(request.Context(), context.DB); err != nil {
+		log.Printf("readyz: %v", err)
+		http.Error(writer, "database unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	fmt.Fprintln(writer, "ok")
+}
+
+func pingDatabase(ctx context.Context, db quiz.Repository) error {
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	return db.Ping(ctx)
+}
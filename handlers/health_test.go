@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"me885/fintech-or-furniture/quiz/database"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthy(t *testing.T) {
+	context := Context{DB: database.InitMemoryDatabase()}
+	context.DB.Close()
+
+	resp := httptest.NewRecorder()
+	context.Healthy(resp, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if resp.Code != http.StatusOK {
+		t.Fatal("healthz should not depend on the database", resp.Code)
+	}
+}
+
+func TestReady(t *testing.T) {
+	context := Context{DB: database.InitMemoryDatabase()}
+
+	resp := httptest.NewRecorder()
+	context.Ready(resp, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if resp.Code != http.StatusOK {
+		t.Fatal(resp.Code, resp.Body)
+	}
+
+	context.DB.Close()
+
+	resp = httptest.NewRecorder()
+	context.Ready(resp, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if resp.Code != http.StatusServiceUnavailable {
+		t.Fatal("readyz should fail without a database", resp.Code)
+	}
+}
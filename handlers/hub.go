@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is a server-sent event. Data is usually an HTML fragment for htmx
+// to swap in, and may be empty for events that only trigger a request.
+type Event struct {
+	Name string
+	Data string
+}
+
+// subscriberBuffer is how many events a subscriber can fall behind by before
+// it misses some.
+const subscriberBuffer = 16
+
+// Hub passes events to whoever is subscribed to their topic. It only reaches
+// subscribers connected to this server process.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan Event]bool
+	closed      bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: map[string]map[chan Event]bool{}}
+}
+
+// Subscribe returns the topic's events and a function to stop receiving
+// them, which closes the channel. Once the hub is closed the channel comes
+// back already closed.
+func (hub *Hub) Subscribe(topic string) (<-chan Event, func()) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	events := make(chan Event, subscriberBuffer)
+	if hub.closed {
+		close(events)
+		return events, func() {}
+	}
+
+	if hub.subscribers[topic] == nil {
+		hub.subscribers[topic] = map[chan Event]bool{}
+	}
+	hub.subscribers[topic][events] = true
+
+	unsubscribe := func() {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+
+		if hub.subscribers[topic][events] {
+			delete(hub.subscribers[topic], events)
+			if len(hub.subscribers[topic]) == 0 {
+				delete(hub.subscribers, topic)
+			}
+			close(events)
+		}
+	}
+
+	return events, unsubscribe
+}
+
+// Publish sends the event to the topic's subscribers without waiting for
+// them, so a subscriber that has fallen too far behind misses it. Publishing
+// to a nil Hub does nothing, for servers without live updates.
+func (hub *Hub) Publish(topic string, event Event) {
+	if hub == nil {
+		return
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for events := range hub.subscribers[topic] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// Close ends every subscription, so that event streams finish and the server
+// can shut down without waiting for their clients to go away. Closing a nil
+// Hub does nothing.
+func (hub *Hub) Close() {
+	if hub == nil {
+		return
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.closed = true
+	for topic, subscribers := range hub.subscribers {
+		for events := range subscribers {
+			close(events)
+		}
+		delete(hub.subscribers, topic)
+	}
+}
+
+// serveEvents streams the topic's events to the client until it goes away.
+// first, if given, is sent as soon as the client connects. render, if given,
+// turns each event into what this client should be sent, for events that
+// only say something changed.
+func (hub *Hub) serveEvents(writer http.ResponseWriter, request *http.Request, topic string, first *Event, render func(Event) (*Event, error)) {
+	flusher, ok := writer.(http.Flusher)
+	if hub == nil || !ok {
+		http.Error(writer, "live updates are not available", http.StatusNotImplemented)
+		return
+	}
+
+	events, unsubscribe := hub.Subscribe(topic)
+	defer unsubscribe()
+
+	// Streams stay open far longer than the server's timeouts allow other
+	// requests, and end when the client or the hub goes away instead.
+	controller := http.NewResponseController(writer)
+	controller.SetReadDeadline(time.Time{})
+	controller.SetWriteDeadline(time.Time{})
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+
+	if first != nil {
+		writeEvent(writer, *first)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			if render != nil {
+				rendered, err := render(event)
+				if err != nil {
+					return
+				}
+				event = *rendered
+			}
+
+			writeEvent(writer, event)
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes the event in the text/event-stream format, which needs a
+// data line for every line of the data.
+func writeEvent(writer http.ResponseWriter, event Event) {
+	fmt.Fprintf(writer, "event: %s\n", event.Name)
+	for _, line := range strings.Split(event.Data, "\n") {
+		fmt.Fprintf(writer, "data: %s\n", line)
+	}
+	fmt.Fprint(writer, "\n")
+}
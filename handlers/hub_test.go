@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHub_PublishReachesTopicSubscribers(t *testing.T) {
+	hub := NewHub()
+
+	events, unsubscribe := hub.Subscribe("room:ABCDE")
+	defer unsubscribe()
+
+	other, unsubscribeOther := hub.Subscribe("room:ZZZZZ")
+	defer unsubscribeOther()
+
+	hub.Publish("room:ABCDE", Event{Name: "question"})
+
+	if event := <-events; event.Name != "question" {
+		t.Fatal(event)
+	}
+
+	select {
+	case event := <-other:
+		t.Fatal("other topics should not get the event", event)
+	default:
+	}
+}
+
+func TestHub_Unsubscribe(t *testing.T) {
+	hub := NewHub()
+
+	events, unsubscribe := hub.Subscribe("room:ABCDE")
+	unsubscribe()
+	unsubscribe()
+
+	if _, ok := <-events; ok {
+		t.Fatal("the channel should be closed")
+	}
+
+	hub.Publish("room:ABCDE", Event{Name: "question"})
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub()
+
+	events, unsubscribe := hub.Subscribe("room:ABCDE")
+	hub.Close()
+	unsubscribe()
+
+	if _, ok := <-events; ok {
+		t.Fatal("the channel should be closed")
+	}
+
+	closed, _ := hub.Subscribe("room:ABCDE")
+	if _, ok := <-closed; ok {
+		t.Fatal("subscribing to a closed hub should get a closed channel")
+	}
+
+	var nilHub *Hub
+	nilHub.Close()
+}
+
+func TestHub_PublishDoesNotBlock(t *testing.T) {
+	hub := NewHub()
+
+	_, unsubscribe := hub.Subscribe("room:ABCDE")
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer*2; i++ {
+		hub.Publish("room:ABCDE", Event{Name: "scores"})
+	}
+
+	var nilHub *Hub
+	nilHub.Publish("room:ABCDE", Event{Name: "scores"})
+}
+
+func TestWriteEvent_SplitsLines(t *testing.T) {
+	resp := httptest.NewRecorder()
+
+	writeEvent(resp, Event{Name: "scores", Data: "<tr>\n<td>bob</td>\n</tr>"})
+
+	if body := resp.Body.String(); body != "event: scores\ndata: <tr>\ndata: <td>bob</td>\ndata: </tr>\n\n" {
+		t.Fatalf("%q", body)
+	}
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"me885/fintech-or-furniture/quiz"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+const playerCookieName = "playerSession"
+
+// recentGames is how many of a player's games their profile lists.
+const recentGames = 20
+
+// maxPasswordHashes is how many passwords are hashed or checked at once.
+// Each takes 64 MiB and most of a CPU for a moment, so registrations and
+// logins beyond it are turned away rather than queued.
+const maxPasswordHashes = 4
+
+var passwordHashing = make(chan struct{}, maxPasswordHashes)
+
+var errTooManyLogins = errors.New("too many players are logging in, try again shortly")
+
+// startPasswordHashing takes one of the maxPasswordHashes slots, which the
+// caller gives back with finishPasswordHashing.
+func startPasswordHashing() error {
+	select {
+	case passwordHashing <- struct{}{}:
+		return nil
+	default:
+		return &requestError{http.StatusServiceUnavailable, errTooManyLogins}
+	}
+}
+
+func finishPasswordHashing() {
+	<-passwordHashing
+}
+
+// currentPlayer returns the logged in player, or nil for guests and expired
+// sessions.
+func (context Context) currentPlayer(request *http.Request) (*quiz.Player, error) {
+	cookie, err := request.Cookie(playerCookieName)
+	if err != nil {
+		return nil, nil
+	}
+
+	session, err := context.DB.GetSession(cookie.Value)
+	if errors.Is(err, quiz.ErrNotExists) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if session.IsExpired(time.Now()) {
+		context.DB.DeleteSession(session.Token)
+		return nil, nil
+	}
+
+	return context.DB.GetPlayerById(session.PlayerId)
+}
+
+// playingAs works out who a new game is played by: the logged in player
+// under their username, or a guest under any name that is not a registered
+// player's. Guests get a player id of 0.
+func (context Context) playingAs(request *http.Request, name string) (string, int64, error) {
+	player, err := context.currentPlayer(request)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if player != nil {
+		return player.Username, player.Id, nil
+	}
+
+	if name == "" {
+		return "", 0, nil
+	}
+
+	_, err = context.DB.GetPlayerByUsername(name)
+	if err == nil {
+		return "", 0, &requestError{http.StatusConflict, fmt.Errorf("%s is a registered player, log in to play as them", name)}
+	}
+	if !errors.Is(err, quiz.ErrNotExists) {
+		return "", 0, err
+	}
+
+	return name, 0, nil
+}
+
+var accountPathRegex = regexp.MustCompile(`^/account/(?:(register|login|logout)/)?$`)
+
+// Account serves everything under /account/:
+//
+//	GET  /account/           the log in and register forms
+//	POST /account/register/  register with a username and password, and log in
+//	POST /account/login/     log in with a username and password
+//	POST /account/logout/    log out
+func (context Context) Account(writer http.ResponseWriter, request *http.Request) {
+	match := accountPathRegex.FindStringSubmatch(request.URL.Path)
+	if match == nil {
+		http.NotFound(writer, request)
+		return
+	}
+
+	method := http.MethodPost
+	if match[1] == "" {
+		method = http.MethodGet
+	}
+
+	if request.Method != method {
+		writer.Header().Set("Allow", method)
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var session *quiz.Session
+	var err error
+
+	switch match[1] {
+	case "":
+		context.render(writer, "account.html", nil)
+		return
+
+	case "register":
+		session, err = context.register(request.PostFormValue("username"), request.PostFormValue("password"))
+
+	case "login":
+		session, err = context.logIn(request.PostFormValue("username"), request.PostFormValue("password"))
+
+	case "logout":
+		if cookie, err := request.Cookie(playerCookieName); err == nil {
+			context.DB.DeleteSession(cookie.Value)
+		}
+
+		context.setCookie(writer, request, &http.Cookie{Name: playerCookieName, Value: "", MaxAge: -1})
+		redirectHome(writer, request)
+		return
+	}
+
+	if err != nil {
+		http.Error(writer, err.Error(), errorStatus(err))
+		return
+	}
+
+	context.setCookie(writer, request, &http.Cookie{Name: playerCookieName, Value: session.Token, Expires: session.Expires})
+	redirectHome(writer, request)
+}
+
+// redirectHome sends the browser back to the front page. htmx is asked to
+// load it in full, so that it shows who is logged in.
+func redirectHome(writer http.ResponseWriter, request *http.Request) {
+	if request.Header.Get("HX-Request") == "true" {
+		writer.Header().Set("HX-Redirect", "/")
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	http.Redirect(writer, request, "/", http.StatusSeeOther)
+}
+
+func (context Context) playerSessionLifetime() time.Duration {
+	if context.PlayerSessionLifetime == 0 {
+		return quiz.SessionLifetime
+	}
+	return context.PlayerSessionLifetime
+}
+
+// register creates a player and logs them in.
+func (context Context) register(username string, password string) (*quiz.Session, error) {
+	if err := quiz.ValidateUsername(username); err != nil {
+		return nil, &requestError{http.StatusBadRequest, err}
+	}
+
+	if err := quiz.ValidatePassword(password); err != nil {
+		return nil, &requestError{http.StatusBadRequest, err}
+	}
+
+	if err := startPasswordHashing(); err != nil {
+		return nil, err
+	}
+	hash, err := quiz.HashPassword(password)
+	finishPasswordHashing()
+	if err != nil {
+		return nil, err
+	}
+
+	player, err := context.DB.CreatePlayer(username, hash)
+	if errors.Is(err, quiz.ErrDuplicate) {
+		return nil, &requestError{http.StatusConflict, fmt.Errorf("the username %s is taken", username)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return context.DB.CreateSession(player.Id, time.Now().Add(context.playerSessionLifetime()))
+}
+
+func (context Context) logIn(username string, password string) (*quiz.Session, error) {
+	player, err := context.DB.GetPlayerByUsername(username)
+	if errors.Is(err, quiz.ErrNotExists) {
+		return nil, &requestError{http.StatusUnauthorized, quiz.ErrInvalidCredentials}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := startPasswordHashing(); err != nil {
+		return nil, err
+	}
+	matches := quiz.CheckPassword(player.PasswordHash, password)
+	finishPasswordHashing()
+
+	if !matches {
+		return nil, &requestError{http.StatusUnauthorized, quiz.ErrInvalidCredentials}
+	}
+
+	return context.DB.CreateSession(player.Id, time.Now().Add(context.playerSessionLifetime()))
+}
+
+var profilePathRegex = regexp.MustCompile(`^/players/([^/]+)/$`)
+
+// Profile shows a registered player's best games and recent history at
+// /players/{username}/.
+func (context Context) Profile(writer http.ResponseWriter, request *http.Request) {
+	match := profilePathRegex.FindStringSubmatch(request.URL.Path)
+	if match == nil {
+		http.NotFound(writer, request)
+		return
+	}
+
+	player, err := context.DB.GetPlayerByUsername(match[1])
+	if errors.Is(err, quiz.ErrNotExists) {
+		http.NotFound(writer, request)
+		return
+	}
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	games, err := context.DB.PlayerGames(player.Id)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var recent []quiz.Game
+	for _, game := range games {
+		if !game.InProgress && len(recent) < recentGames {
+			recent = append(recent, game)
+		}
+	}
+
+	modes := context.modes()
+	context.render(writer, "profile.html", quiz.ProfilePageStruct{Player: *player, Bests: quiz.PersonalBests(games, modes), Recent: recent, Modes: modes})
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"me885/fintech-or-furniture/quiz"
+	"me885/fintech-or-furniture/quiz/database"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func playerCookie(resp *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range resp.Result().Cookies() {
+		if cookie.Name == playerCookieName {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// registerTestPlayer registers alice and returns her session cookie.
+func registerTestPlayer(t *testing.T, handlerContext Context) *http.Cookie {
+	resp := formRequest(handlerContext.Account, "POST", "/account/register/", url.Values{"username": {"alice"}, "password": {"correct horse"}}, nil)
+	if resp.Code != http.StatusSeeOther {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	cookie := playerCookie(resp)
+	if cookie == nil || cookie.Value == "" || !cookie.HttpOnly {
+		t.Fatal(resp.Result().Cookies())
+	}
+	return cookie
+}
+
+func TestAccount_Register(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+	cookie := registerTestPlayer(t, handlerContext)
+
+	player, err := handlerContext.DB.GetPlayerByUsername("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if player.PasswordHash == "correct horse" || !quiz.CheckPassword(player.PasswordHash, "correct horse") {
+		t.Fatal(player.PasswordHash)
+	}
+
+	resp := formRequest(handlerContext.RootPage, "GET", "/", nil, cookie)
+	if !strings.Contains(resp.Body.String(), "Playing as alice") {
+		t.Fatal(resp.Body.String())
+	}
+
+	resp = formRequest(handlerContext.Account, "POST", "/account/register/", url.Values{"username": {"Alice"}, "password": {"another password"}}, nil)
+	if resp.Code != http.StatusConflict {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	resp = formRequest(handlerContext.Account, "POST", "/account/register/", url.Values{"username": {"bob"}, "password": {"short"}}, nil)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+}
+
+func TestAccount_LogInAndOut(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+	registerTestPlayer(t, handlerContext)
+
+	resp := formRequest(handlerContext.Account, "POST", "/account/login/", url.Values{"username": {"alice"}, "password": {"wrong password"}}, nil)
+	if resp.Code != http.StatusUnauthorized || playerCookie(resp) != nil {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	resp = formRequest(handlerContext.Account, "POST", "/account/login/", url.Values{"username": {"nobody"}, "password": {"correct horse"}}, nil)
+	if resp.Code != http.StatusUnauthorized {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	resp = formRequest(handlerContext.Account, "POST", "/account/login/", url.Values{"username": {"ALICE"}, "password": {"correct horse"}}, nil)
+	if resp.Code != http.StatusSeeOther {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+	cookie := playerCookie(resp)
+
+	resp = formRequest(handlerContext.Account, "POST", "/account/logout/", nil, cookie)
+	if resp.Code != http.StatusSeeOther || playerCookie(resp).MaxAge >= 0 {
+		t.Fatal(resp.Code, resp.Result().Cookies())
+	}
+
+	if _, err := handlerContext.DB.GetSession(cookie.Value); err != quiz.ErrNotExists {
+		t.Fatal(err)
+	}
+
+	resp = formRequest(handlerContext.RootPage, "GET", "/", nil, cookie)
+	if strings.Contains(resp.Body.String(), "Playing as") {
+		t.Fatal(resp.Body.String())
+	}
+}
+
+func TestAccount_TooManyLogins(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+	registerTestPlayer(t, handlerContext)
+
+	for i := 0; i < maxPasswordHashes; i++ {
+		passwordHashing <- struct{}{}
+	}
+	t.Cleanup(func() {
+		for len(passwordHashing) > 0 {
+			finishPasswordHashing()
+		}
+	})
+
+	resp := formRequest(handlerContext.Account, "POST", "/account/login/", url.Values{"username": {"alice"}, "password": {"correct horse"}}, nil)
+	if resp.Code != http.StatusServiceUnavailable {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	resp = formRequest(handlerContext.Account, "POST", "/account/register/", url.Values{"username": {"bob"}, "password": {"correct horse"}}, nil)
+	if resp.Code != http.StatusServiceUnavailable {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	finishPasswordHashing()
+
+	resp = formRequest(handlerContext.Account, "POST", "/account/login/", url.Values{"username": {"alice"}, "password": {"correct horse"}}, nil)
+	if resp.Code != http.StatusSeeOther {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+}
+
+func TestAccount_HtmxRedirect(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+
+	req, _ := http.NewRequest("POST", "/account/register/", strings.NewReader(url.Values{"username": {"alice"}, "password": {"correct horse"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+
+	resp := httptest.NewRecorder()
+	handlerContext.Account(resp, req)
+
+	if resp.Code != http.StatusNoContent || resp.Header().Get("HX-Redirect") != "/" {
+		t.Fatal(resp.Code, resp.Header())
+	}
+}
+
+func TestAccount_ExpiredSession(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+	player, err := handlerContext.DB.CreatePlayer("alice", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := handlerContext.DB.CreateSession(player.Id, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := formRequest(handlerContext.RootPage, "GET", "/", nil, &http.Cookie{Name: playerCookieName, Value: session.Token})
+	if strings.Contains(resp.Body.String(), "Playing as") {
+		t.Fatal(resp.Body.String())
+	}
+}
+
+func TestNewGame_LinksPlayer(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+	cookie := registerTestPlayer(t, handlerContext)
+
+	resp := formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"someone else"}}, cookie)
+	if resp.Code != http.StatusOK {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	games, _ := handlerContext.DB.AllGames()
+	player, _ := handlerContext.DB.GetPlayerByUsername("alice")
+	if len(games) != 1 || games[0].PlayerName != "alice" || games[0].PlayerId != player.Id {
+		t.Fatal(games)
+	}
+}
+
+func TestNewGame_GuestCannotUseRegisteredName(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+	registerTestPlayer(t, handlerContext)
+
+	resp := formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"Alice"}}, nil)
+	if resp.Code != http.StatusConflict {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	resp = formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"bob"}}, nil)
+	if resp.Code != http.StatusOK {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	games, _ := handlerContext.DB.AllGames()
+	if len(games) != 1 || games[0].PlayerId != 0 {
+		t.Fatal(games)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+	registerTestPlayer(t, handlerContext)
+	player, _ := handlerContext.DB.GetPlayerByUsername("alice")
+
+	for _, score := range []int64{4, 7} {
+		game, err := handlerContext.DB.CreateGame(quiz.NewGame{PlayerName: "alice", Mode: "classic", PackId: 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		game.PlayerId = player.Id
+		game.Score = score
+		game.QuestionsAnswered = 10
+		game.InProgress = false
+		game.Completed = time.Now()
+		handlerContext.DB.UpdateGame(game)
+	}
+
+	resp := formRequest(handlerContext.Profile, "GET", "/players/Alice/", nil, nil)
+	html := resp.Body.String()
+	if resp.Code != http.StatusOK || !strings.Contains(html, "alice") || !strings.Contains(html, "7/10") || !strings.Contains(html, "4/10") {
+		t.Fatal(resp.Code, html)
+	}
+
+	resp = formRequest(handlerContext.Profile, "GET", "/players/nobody/", nil, nil)
+	if resp.Code != http.StatusNotFound {
+		t.Fatal(resp.Code)
+	}
+}
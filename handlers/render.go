@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"me885/fintech-or-furniture/assets"
+	"net/http"
+	"sync"
+)
+
+// templatePages lists every page handlers render, by the template file it
+// starts from, with the files holding the templates it uses.
+var templatePages = map[string][]string{
+	"index.html":                {},
+	"quizQuestion.html":         {},
+	"nextQuestion.html":         {},
+	"endPage.html":              {},
+	"leaderboard.html":          {"leaderboardBody.html"},
+	"leaderboardTable.html":     {"leaderboardBody.html"},
+	"leaderboardBody.html":      {},
+	"dailyLeaderboard.html":     {"leaderboardBody.html"},
+	"account.html":              {},
+	"profile.html":              {},
+	"room.html":                 {"roomWaiting.html", "roomPlayers.html"},
+	"roomWaiting.html":          {},
+	"roomPlayers.html":          {},
+	"admin.html":                {"adminQuestions.html"},
+	"adminAudit.html":           {},
+	"adminQuestionTable.html":   {"adminQuestions.html"},
+	"adminQuestionRow.html":     {"adminQuestions.html"},
+	"adminQuestionEditRow.html": {"adminQuestions.html"},
+}
+
+// Renderer renders the pages in templatePages with html/template, which
+// escapes whatever players type in before it reaches anyone's browser.
+type Renderer struct {
+	files fs.FS
+	// static gives the URLs templates link to static files with.
+	static *StaticFiles
+	// reload parses pages again every time they are rendered, so that
+	// template changes show without restarting the server.
+	reload bool
+	pages  map[string]*template.Template
+}
+
+// NewRenderer parses every page from files up front, failing if any of them
+// do not parse. Templates link to static files with {{ asset "name" }}. With
+// reload, pages are parsed again on every render.
+func NewRenderer(files fs.FS, static *StaticFiles, reload bool) (*Renderer, error) {
+	renderer := &Renderer{files: files, static: static, reload: reload, pages: map[string]*template.Template{}}
+
+	for page := range templatePages {
+		parsed, err := renderer.parse(page)
+		if err != nil {
+			return nil, err
+		}
+		renderer.pages[page] = parsed
+	}
+
+	return renderer, nil
+}
+
+func (renderer *Renderer) parse(page string) (*template.Template, error) {
+	partials, ok := templatePages[page]
+	if !ok {
+		return nil, fmt.Errorf("unknown page %s", page)
+	}
+
+	return template.New(page).Funcs(template.FuncMap{"asset": renderer.asset}).ParseFS(renderer.files, append([]string{page}, partials...)...)
+}
+
+func (renderer *Renderer) asset(name string) string {
+	if renderer.static == nil {
+		return "/static/" + name
+	}
+	return renderer.static.URL(name)
+}
+
+// ExecuteTemplate renders the template called name from page into writer.
+// A page's own template is called by its file name.
+func (renderer *Renderer) ExecuteTemplate(writer io.Writer, page string, name string, data any) error {
+	parsed, ok := renderer.pages[page]
+	if renderer.reload || !ok {
+		var err error
+		if parsed, err = renderer.parse(page); err != nil {
+			return err
+		}
+	}
+
+	return parsed.ExecuteTemplate(writer, name, data)
+}
+
+// defaultRenderer serves contexts without a Renderer of their own, such as
+// in tests, from the embedded templates.
+var defaultRenderer = sync.OnceValues(func() (*Renderer, error) {
+	static, err := NewStaticFiles(assets.Static(""))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRenderer(assets.Templates(""), static, false)
+})
+
+func (context Context) renderer() (*Renderer, error) {
+	if context.Templates != nil {
+		return context.Templates, nil
+	}
+	return defaultRenderer()
+}
+
+// renderTemplate renders the template called name from page. It is rendered
+// in full before anything is written, so that a page failing part way
+// through is a clean 500 rather than half a page.
+func (context Context) renderTemplate(writer http.ResponseWriter, page string, name string, data any) {
+	var body bytes.Buffer
+	if err := context.executeTemplate(&body, page, name, data); err != nil {
+		log.Printf("rendering %s: %v", name, err)
+		http.Error(writer, "failed to render page", http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	body.WriteTo(writer)
+}
+
+// render renders a page from templatePages.
+func (context Context) render(writer http.ResponseWriter, page string, data any) {
+	context.renderTemplate(writer, page, page, data)
+}
+
+func (context Context) executeTemplate(writer io.Writer, page string, name string, data any) error {
+	renderer, err := context.renderer()
+	if err != nil {
+		return err
+	}
+
+	return renderer.ExecuteTemplate(writer, page, name, data)
+}
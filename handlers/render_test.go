@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"me885/fintech-or-furniture/assets"
+	"me885/fintech-or-furniture/quiz"
+	"me885/fintech-or-furniture/quiz/database"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestNewRenderer_ParsesEveryPage(t *testing.T) {
+	renderer, err := NewRenderer(assets.Templates(""), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(renderer.pages) != len(templatePages) {
+		t.Fatal(renderer.pages)
+	}
+}
+
+func TestNewRenderer_MissingTemplate(t *testing.T) {
+	if _, err := NewRenderer(fstest.MapFS{}, nil, false); err == nil {
+		t.Fatal("pages that are missing should fail to parse")
+	}
+}
+
+func TestRenderer_Reload(t *testing.T) {
+	files := fstest.MapFS{"endPage.html": &fstest.MapFile{Data: []byte("before")}}
+	renderer := &Renderer{files: files, reload: true}
+
+	var body strings.Builder
+	renderer.ExecuteTemplate(&body, "endPage.html", "endPage.html", nil)
+
+	files["endPage.html"] = &fstest.MapFile{Data: []byte("after")}
+
+	body.Reset()
+	if err := renderer.ExecuteTemplate(&body, "endPage.html", "endPage.html", nil); err != nil || body.String() != "after" {
+		t.Fatal(body.String(), err)
+	}
+}
+
+func TestRender_ExecuteError(t *testing.T) {
+	files := fstest.MapFS{"endPage.html": &fstest.MapFile{Data: []byte("{{ .Missing.Field }}")}}
+	handlerContext := Context{DB: database.InitMemoryDatabase(), Templates: &Renderer{files: files, reload: true}}
+
+	resp := httptest.NewRecorder()
+	handlerContext.render(resp, "endPage.html", quiz.Game{})
+
+	if resp.Code != http.StatusInternalServerError || strings.Contains(resp.Body.String(), "Missing") {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+}
+
+func TestLeaderboard_EscapesPlayerNames(t *testing.T) {
+	testDb := database.InitMemoryDatabase()
+
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: `<script>alert("hi")</script>`, Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+	game.QuestionsAnswered = 10
+	game.Score = 6
+	game.InProgress = false
+	game.Completed = time.Now()
+	testDb.UpdateGame(game)
+
+	handlerContext := Context{DB: testDb}
+
+	req, _ := http.NewRequest("GET", "/leaderboard/?time-select=start of day", nil)
+	resp := httptest.NewRecorder()
+	handlerContext.Leaderboard(resp, req)
+
+	html := resp.Body.String()
+	if strings.Contains(html, "<script>alert") || !strings.Contains(html, "&lt;script&gt;alert") {
+		t.Fatal(html)
+	}
+}
@@ -0,0 +1,488 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"me885/fintech-or-furniture/quiz"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// roomCodeAttempts is how many random codes to try before giving up on
+// opening a room.
+const roomCodeAttempts = 5
+
+// errWaitingForRoom is returned instead of a question to players in a room
+// who have answered the current one, until everyone else has too.
+var errWaitingForRoom = &requestError{http.StatusConflict, errors.New("waiting for the other players in the room")}
+
+func roomTopic(code string) string {
+	return "room:" + code
+}
+
+var roomPathRegex = regexp.MustCompile(`^/rooms/(?:(join)/|([A-Z0-9]+)/(start|events)/)?$`)
+
+// Rooms serves everything under /rooms/:
+//
+//	POST /rooms/               open a room, with the host's name and a pack
+//	POST /rooms/join/          join a room, with a name and the room's code
+//	POST /rooms/{code}/start/  start asking questions, for the host only
+//	GET  /rooms/{code}/events/ server-sent events as the room changes
+//
+// Players answer and fetch questions with /answer/ and /next-question/ like
+// any other game.
+func (context Context) Rooms(writer http.ResponseWriter, request *http.Request) {
+	match := roomPathRegex.FindStringSubmatch(request.URL.Path)
+	if match == nil {
+		http.NotFound(writer, request)
+		return
+	}
+
+	method := http.MethodPost
+	if match[3] == "events" {
+		method = http.MethodGet
+	}
+
+	if request.Method != method {
+		writer.Header().Set("Allow", method)
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case match[1] == "join":
+		playerName, playerId, err := context.playingAs(request, request.PostFormValue("name"))
+		if err != nil {
+			http.Error(writer, err.Error(), errorStatus(err))
+			return
+		}
+
+		page, err := context.joinRoom(writer, request, playerName, playerId, request.PostFormValue("code"))
+		if err != nil {
+			http.Error(writer, err.Error(), errorStatus(err))
+			return
+		}
+
+		context.render(writer, "room.html", page)
+
+	case match[2] == "":
+		hostName, playerId, err := context.playingAs(request, request.PostFormValue("name"))
+		if err != nil {
+			http.Error(writer, err.Error(), errorStatus(err))
+			return
+		}
+
+		page, err := context.openRoom(writer, request, hostName, playerId, request.PostFormValue("pack"))
+		if err != nil {
+			http.Error(writer, err.Error(), errorStatus(err))
+			return
+		}
+
+		context.render(writer, "room.html", page)
+
+	case match[3] == "start":
+		game, err := getGameIfAuthed(request, context.DB)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		page, err := context.startRoom(game, match[2])
+		if err != nil {
+			http.Error(writer, err.Error(), errorStatus(err))
+			return
+		}
+
+		context.render(writer, "quizQuestion.html", page)
+
+	case match[3] == "events":
+		room, err := context.DB.GetRoomByCode(match[2])
+		if errors.Is(err, quiz.ErrNotExists) {
+			http.NotFound(writer, request)
+			return
+		}
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Send the players as they are now, in case anything changed
+		// between the page loading and the stream connecting.
+		scores, err := context.roomScoresEvent(room)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		context.Hub.serveEvents(writer, request, roomTopic(room.Code), scores, nil)
+	}
+}
+
+// openRoom creates the host's game in the request's game session and a room
+// for it, in the lobby. Like startGame, a playerId of 0 is a guest.
+func (context Context) openRoom(writer http.ResponseWriter, request *http.Request, hostName string, playerId int64, packSlug string) (*quiz.RoomPageStruct, error) {
+	if hostName == "" {
+		return nil, &requestError{http.StatusBadRequest, errors.New("name is required")}
+	}
+
+	if packSlug == "" {
+		packSlug = quiz.DefaultPack.Slug
+	}
+
+	pack, err := context.DB.GetPackBySlug(packSlug)
+	if errors.Is(err, quiz.ErrNotExists) {
+		return nil, &requestError{http.StatusBadRequest, fmt.Errorf("unknown pack %q", packSlug)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := context.DB.CountEnabledQuestions(pack.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, _ := context.modes().Get(quiz.DefaultMode)
+	if err := quiz.CheckBankSize(mode, available); err != nil {
+		return nil, &requestError{http.StatusServiceUnavailable, err}
+	}
+
+	game, err := context.createGame(writer, request, quiz.NewGame{PlayerName: hostName, Mode: mode.Name, PackId: pack.Id, PlayerId: playerId})
+	if err != nil {
+		return nil, err
+	}
+
+	var room *quiz.Room
+	for attempt := 0; attempt < roomCodeAttempts; attempt++ {
+		room, err = context.DB.CreateRoom(quiz.NewRoomCode(), pack.Id, game.Id)
+		if !errors.Is(err, quiz.ErrDuplicate) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	game.RoomCode = room.Code
+	if _, err := context.DB.UpdateGame(game); err != nil {
+		return nil, err
+	}
+
+	return &quiz.RoomPageStruct{Room: *room, Game: *game, Players: []quiz.Game{*game}}, nil
+}
+
+// joinRoom adds a player to a room that has not started yet, with a game in
+// the request's game session.
+func (context Context) joinRoom(writer http.ResponseWriter, request *http.Request, playerName string, playerId int64, code string) (*quiz.RoomPageStruct, error) {
+	if playerName == "" {
+		return nil, &requestError{http.StatusBadRequest, errors.New("name is required")}
+	}
+
+	code = quiz.NormaliseRoomCode(code)
+
+	room, err := context.DB.GetRoomByCode(code)
+	if errors.Is(err, quiz.ErrNotExists) {
+		return nil, &requestError{http.StatusNotFound, fmt.Errorf("there is no room %q", code)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if room.State != quiz.RoomLobby {
+		return nil, &requestError{http.StatusConflict, fmt.Errorf("room %s has already started", code)}
+	}
+
+	// Creating the game checks again, in case the room started or someone
+	// took the name meanwhile.
+	game, err := context.createGame(writer, request, quiz.NewGame{PlayerName: playerName, Mode: quiz.DefaultMode, PackId: room.PackId, RoomCode: code, PlayerId: playerId})
+	if errors.Is(err, quiz.ErrRoomStarted) {
+		return nil, &requestError{http.StatusConflict, fmt.Errorf("room %s has already started", code)}
+	}
+	if errors.Is(err, quiz.ErrDuplicate) {
+		return nil, &requestError{http.StatusConflict, fmt.Errorf("someone called %s is already in room %s", playerName, code)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	players, err := context.DB.RoomGames(code)
+	if err != nil {
+		return nil, err
+	}
+	context.publishRoomScores(room, players)
+
+	return &quiz.RoomPageStruct{Room: *room, Game: *game, Players: players}, nil
+}
+
+// startRoom asks the room's first question. Only the host can start it.
+func (context Context) startRoom(game *quiz.Game, code string) (*quiz.QuestionPageStruct, error) {
+	if game.RoomCode != code {
+		return nil, &requestError{http.StatusForbidden, fmt.Errorf("you are not in room %s", code)}
+	}
+
+	room, err := context.DB.GetRoomByCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if room.HostGameId != game.Id {
+		return nil, &requestError{http.StatusForbidden, errors.New("only the host can start the room")}
+	}
+
+	if room.State != quiz.RoomLobby {
+		return nil, &requestError{http.StatusConflict, fmt.Errorf("room %s has already started", code)}
+	}
+
+	if err := context.advanceRoom(room); err != nil {
+		return nil, err
+	}
+
+	// Advancing the room served the question to the host's game too.
+	game, err = context.DB.GetGameById(game.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return context.currentQuestion(game)
+}
+
+// roomAnswered moves the room on once every player still playing has
+// answered its current question, and otherwise just shares the new scores.
+// It reports whether players are still to answer.
+func (context Context) roomAnswered(code string) (bool, error) {
+	room, err := context.DB.GetRoomByCode(code)
+	if err != nil {
+		return false, err
+	}
+
+	players, err := context.DB.RoomGames(code)
+	if err != nil {
+		return false, err
+	}
+
+	waiting, err := context.moveRoomOn(room, players)
+	if waiting {
+		context.publishRoomScores(room, players)
+	}
+	return waiting, err
+}
+
+// moveRoomOn asks the room's next question once every player still playing
+// has answered the current one, or once the time to answer it has run out,
+// counting it as missed for the players who have not. It reports whether the
+// room is still waiting for players to answer.
+func (context Context) moveRoomOn(room *quiz.Room, players []quiz.Game) (bool, error) {
+	if room.State != quiz.RoomPlaying {
+		return false, nil
+	}
+
+	// Players who have answered every question asked so far are done, even
+	// if another server is still serving them the next one.
+	var waitingFor []*quiz.Game
+	for i := range players {
+		if players[i].InProgress && players[i].QuestionsAnswered < room.QuestionsAsked {
+			waitingFor = append(waitingFor, &players[i])
+		}
+	}
+
+	if len(waitingFor) > 0 {
+		if !room.IsQuestionOutOfTime(time.Now()) {
+			return true, nil
+		}
+
+		for _, game := range waitingFor {
+			if err := context.missQuestions(game, room); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return false, context.advanceRoom(room)
+}
+
+// roomDue moves the game's room on if it is due to, for a player waiting
+// for the next question, and returns the game as it is afterwards.
+func (context Context) roomDue(game *quiz.Game) (*quiz.Game, error) {
+	room, err := context.DB.GetRoomByCode(game.RoomCode)
+	if err != nil {
+		return nil, err
+	}
+
+	players, err := context.DB.RoomGames(room.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	if waiting, err := context.moveRoomOn(room, players); err != nil || waiting {
+		return game, err
+	}
+
+	return context.DB.GetGameById(game.Id)
+}
+
+// missQuestions counts the questions the room has asked that the player has
+// not answered as wrong answers. If the player answers at the same time,
+// whichever claims the question first counts.
+func (context Context) missQuestions(game *quiz.Game, room *quiz.Room) error {
+	if game.CurrentQuestionId != 0 {
+		err := context.DB.ClaimCurrentQuestion(game.Id, game.CurrentQuestionId)
+		if errors.Is(err, quiz.ErrUpdateFailed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		game.CurrentQuestionId = 0
+	}
+
+	quiz.MissQuestions(game, room.QuestionsAsked-game.QuestionsAnswered)
+
+	if quiz.IsGameComplete(game, context.modes().For(*game)) {
+		context.DB.RemoveGameQuestions(game.Id)
+	}
+
+	game.Completed = time.Now()
+
+	if _, err := context.DB.UpdateGame(game); err != nil {
+		return err
+	}
+
+	if !game.InProgress {
+		context.Bus.PublishGameCompleted(GameCompleted{Game: *game})
+	}
+
+	return nil
+}
+
+// advanceRoom serves the room's next question to every player still
+// playing, or finishes the room when nobody is or the pack has run out.
+// Rooms pick uniformly, as the players' games differ in how well they are
+// going. Servers moving the same room on at once pick the same question,
+// and only the first to save the room goes on to serve it.
+func (context Context) advanceRoom(room *quiz.Room) error {
+	players, err := context.DB.RoomGames(room.Code)
+	if err != nil {
+		return err
+	}
+
+	var playing []*quiz.Game
+	for i := range players {
+		if players[i].InProgress {
+			playing = append(playing, &players[i])
+		}
+	}
+
+	var questions []quiz.Question
+	if len(playing) > 0 {
+		// Everyone still playing has been asked the same questions.
+		if questions, err = context.DB.GetUnansweredQuestions(playing[0].Id); err != nil {
+			return err
+		}
+	}
+
+	previous := *room
+	now := time.Now().UTC()
+
+	if len(questions) == 0 {
+		room.State = quiz.RoomFinished
+		room.CurrentQuestionId = 0
+	} else {
+		question := quiz.UniformSelector{}.Select(questions, quiz.Game{}, room.QuestionRand())
+
+		room.State = quiz.RoomPlaying
+		room.CurrentQuestionId = question.Id
+		room.QuestionsAsked++
+		room.QuestionServed = now
+	}
+
+	_, err = context.DB.UpdateRoom(room, previous)
+	if errors.Is(err, quiz.ErrUpdateFailed) {
+		// Someone else has moved the room on already.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Read the players again in case one joined as the room started.
+	if players, err = context.DB.RoomGames(room.Code); err != nil {
+		return err
+	}
+
+	for i := range players {
+		game := &players[i]
+		if !game.InProgress {
+			continue
+		}
+
+		if room.State == quiz.RoomFinished {
+			game.OutOfQuestions = true
+			if err := context.finishGame(game); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := context.DB.AddGameQuestion(game.Id, room.CurrentQuestionId); err != nil {
+			return err
+		}
+
+		game.CurrentQuestionId = room.CurrentQuestionId
+		game.QuestionServed = now
+		if _, err := context.DB.UpdateGame(game); err != nil {
+			return err
+		}
+	}
+
+	context.publishRoomScores(room, players)
+	context.Hub.Publish(roomTopic(room.Code), Event{Name: "question"})
+
+	return nil
+}
+
+func (context Context) roomScoresEvent(room *quiz.Room) (*Event, error) {
+	players, err := context.DB.RoomGames(room.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	return context.renderRoomScores(room, players)
+}
+
+// publishRoomScores shares the players and their scores with everyone in the
+// room.
+func (context Context) publishRoomScores(room *quiz.Room, players []quiz.Game) {
+	if context.Hub == nil {
+		return
+	}
+
+	event, err := context.renderRoomScores(room, players)
+	if err != nil {
+		return
+	}
+
+	context.Hub.Publish(roomTopic(room.Code), *event)
+}
+
+func (context Context) renderRoomScores(room *quiz.Room, players []quiz.Game) (*Event, error) {
+	var data bytes.Buffer
+	if err := context.executeTemplate(&data, "roomPlayers.html", "roomPlayers", quiz.RoomPageStruct{Room: *room, Players: players}); err != nil {
+		return nil, err
+	}
+
+	return &Event{Name: "scores", Data: data.String()}, nil
+}
+
+// roomWaiting is shown to a player in a room between questions, or before
+// the host starts.
+func (context Context) roomWaiting(writer http.ResponseWriter, game *quiz.Game) {
+	room, err := context.DB.GetRoomByCode(game.RoomCode)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	context.renderTemplate(writer, "roomWaiting.html", "roomWaiting", quiz.RoomPageStruct{Room: *room, Game: *game})
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"me885/fintech-or-furniture/quiz"
+	"me885/fintech-or-furniture/quiz/database"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// responseSession returns the game session cookie a response set.
+func responseSession(t *testing.T, resp *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range resp.Result().Cookies() {
+		if cookie.Name == gameCookieName {
+			return cookie
+		}
+	}
+
+	t.Fatal("no gameSession cookie", resp.Body.String())
+	return nil
+}
+
+// sessionGame reads back the newest game of the session.
+func sessionGame(t *testing.T, db quiz.Repository, session *http.Cookie) *quiz.Game {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.AddCookie(session)
+
+	game, err := getGameIfAuthed(req, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return game
+}
+
+// openTestRoom has host open a room and guest join it, returning their
+// session cookies.
+func openTestRoom(t *testing.T, handlerContext Context) (*http.Cookie, *http.Cookie) {
+	resp := formRequest(handlerContext.Rooms, "POST", "/rooms/", url.Values{"name": {"host"}}, nil)
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), "Start") {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+	host := responseSession(t, resp)
+	hostGame := sessionGame(t, handlerContext.DB, host)
+
+	resp = formRequest(handlerContext.Rooms, "POST", "/rooms/join/", url.Values{"name": {"guest"}, "code": {strings.ToLower(hostGame.RoomCode)}}, nil)
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), "Waiting for the host") {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+	guest := responseSession(t, resp)
+	guestGame := sessionGame(t, handlerContext.DB, guest)
+
+	if hostGame.RoomCode == "" || guestGame.RoomCode != hostGame.RoomCode {
+		t.Fatal(hostGame, guestGame)
+	}
+
+	return host, guest
+}
+
+func TestRooms_Join(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase(), Hub: NewHub()}
+
+	host, _ := openTestRoom(t, handlerContext)
+	code := sessionGame(t, handlerContext.DB, host).RoomCode
+
+	resp := formRequest(handlerContext.Rooms, "POST", "/rooms/join/", url.Values{"name": {"GUEST"}, "code": {code}}, nil)
+	if resp.Code != http.StatusConflict {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	resp = formRequest(handlerContext.Rooms, "POST", "/rooms/join/", url.Values{"name": {"nobody"}, "code": {"ZZZZZ"}}, nil)
+	if resp.Code != http.StatusNotFound {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	players, _ := handlerContext.DB.RoomGames(code)
+	if len(players) != 2 {
+		t.Fatal(players)
+	}
+}
+
+func TestRooms_OnlyHostStarts(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase(), Hub: NewHub()}
+
+	host, guest := openTestRoom(t, handlerContext)
+	code := sessionGame(t, handlerContext.DB, host).RoomCode
+
+	resp := formRequest(handlerContext.Rooms, "POST", "/rooms/"+code+"/start/", nil, guest)
+	if resp.Code != http.StatusForbidden {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	// Until the room starts, players are not served questions of their own.
+	resp = formRequest(handlerContext.NextQuestion, "GET", "/next-question/", nil, guest)
+	if !strings.Contains(resp.Body.String(), "Waiting for the host") || sessionGame(t, handlerContext.DB, guest).CurrentQuestionId != 0 {
+		t.Fatal(resp.Body.String())
+	}
+}
+
+func TestRooms_PlayInLockstep(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase(), Hub: NewHub()}
+
+	host, guest := openTestRoom(t, handlerContext)
+	code := sessionGame(t, handlerContext.DB, host).RoomCode
+
+	events, unsubscribe := handlerContext.Hub.Subscribe(roomTopic(code))
+	defer unsubscribe()
+
+	resp := formRequest(handlerContext.Rooms, "POST", "/rooms/"+code+"/start/", nil, host)
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), "#room-stage") {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	if !hasEvent(events, "question") {
+		t.Fatal("players should be told the room has started")
+	}
+
+	resp = formRequest(handlerContext.Rooms, "POST", "/rooms/join/", url.Values{"name": {"late"}, "code": {code}}, nil)
+	if resp.Code != http.StatusConflict {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	for i := 0; i < 10; i++ {
+		hostGame := sessionGame(t, handlerContext.DB, host)
+		guestGame := sessionGame(t, handlerContext.DB, guest)
+
+		if hostGame.CurrentQuestionId == 0 || guestGame.CurrentQuestionId != hostGame.CurrentQuestionId {
+			t.Fatal(i, hostGame, guestGame)
+		}
+		answerPath := "/answer/" + strconv.FormatInt(hostGame.CurrentQuestionId, 10) + "/?answer=" + formatAnswer(quiz.Fintech)
+
+		resp = formRequest(handlerContext.Answer, "POST", answerPath, nil, host)
+		if resp.Code != http.StatusOK {
+			t.Fatal(resp.Code, resp.Body.String())
+		}
+		if i < 9 && !strings.Contains(resp.Body.String(), "Waiting for the other players") {
+			t.Fatal(resp.Body.String())
+		}
+
+		if sessionGame(t, handlerContext.DB, host).CurrentQuestionId != 0 {
+			t.Fatal("the room should wait for the guest")
+		}
+
+		resp = formRequest(handlerContext.NextQuestion, "GET", "/next-question/", nil, host)
+		if i < 9 && !strings.Contains(resp.Body.String(), "Waiting for the other players") {
+			t.Fatal(resp.Body.String())
+		}
+
+		resp = formRequest(handlerContext.Answer, "POST", answerPath, nil, guest)
+		if resp.Code != http.StatusOK || strings.Contains(resp.Body.String(), "Waiting for the other players") {
+			t.Fatal(resp.Code, resp.Body.String())
+		}
+	}
+
+	room, _ := handlerContext.DB.GetRoomByCode(code)
+	if room.State != quiz.RoomFinished || room.QuestionsAsked != 10 {
+		t.Fatal(room)
+	}
+
+	for _, session := range []*http.Cookie{host, guest} {
+		if game := sessionGame(t, handlerContext.DB, session); game.InProgress || game.QuestionsAnswered != 10 {
+			t.Fatal(game)
+		}
+	}
+}
+
+func TestRooms_MovesOnAfterDeadline(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase(), Hub: NewHub()}
+
+	host, guest := openTestRoom(t, handlerContext)
+	code := sessionGame(t, handlerContext.DB, host).RoomCode
+
+	formRequest(handlerContext.Rooms, "POST", "/rooms/"+code+"/start/", nil, host)
+
+	first := sessionGame(t, handlerContext.DB, host).CurrentQuestionId
+	answerPath := "/answer/" + strconv.FormatInt(first, 10) + "/?answer=" + formatAnswer(quiz.Fintech)
+	formRequest(handlerContext.Answer, "POST", answerPath, nil, host)
+
+	resp := formRequest(handlerContext.NextQuestion, "GET", "/next-question/", nil, host)
+	if !strings.Contains(resp.Body.String(), "Waiting for the other players") {
+		t.Fatal(resp.Body.String())
+	}
+
+	// The guest has left without answering, and the time runs out.
+	room, _ := handlerContext.DB.GetRoomByCode(code)
+	room.QuestionServed = time.Now().Add(-quiz.RoomQuestionTimeLimit - time.Second)
+	if _, err := handlerContext.DB.UpdateRoom(room, *room); err != nil {
+		t.Fatal(err)
+	}
+
+	resp = formRequest(handlerContext.NextQuestion, "GET", "/next-question/", nil, host)
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), "#room-stage") {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	hostGame := sessionGame(t, handlerContext.DB, host)
+	guestGame := sessionGame(t, handlerContext.DB, guest)
+	if hostGame.CurrentQuestionId == first || guestGame.CurrentQuestionId != hostGame.CurrentQuestionId {
+		t.Fatal(hostGame, guestGame)
+	}
+	if guestGame.QuestionsAnswered != 1 || guestGame.Mistakes != 1 || guestGame.Score != 0 {
+		t.Fatal(guestGame)
+	}
+
+	// The guest's late answer to the first question no longer counts.
+	resp = formRequest(handlerContext.Answer, "POST", answerPath, nil, guest)
+	if resp.Code != http.StatusConflict {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+}
+
+func TestRooms_MovedOnOnce(t *testing.T) {
+	db := database.InitMemoryDatabase()
+	handlerContext := Context{DB: db, Hub: NewHub()}
+
+	host, guest := openTestRoom(t, handlerContext)
+	code := sessionGame(t, db, host).RoomCode
+
+	formRequest(handlerContext.Rooms, "POST", "/rooms/"+code+"/start/", nil, host)
+
+	unanswered, _ := db.GetUnansweredQuestions(sessionGame(t, db, host).Id)
+
+	// Two servers see everyone answer and move the room on at once.
+	seen, _ := db.GetRoomByCode(code)
+	alsoSeen := *seen
+
+	if err := handlerContext.advanceRoom(seen); err != nil {
+		t.Fatal(err)
+	}
+	if err := (Context{DB: db, Hub: NewHub()}).advanceRoom(&alsoSeen); err != nil {
+		t.Fatal(err)
+	}
+
+	room, _ := db.GetRoomByCode(code)
+	if room.QuestionsAsked != 2 {
+		t.Fatal(room)
+	}
+
+	for _, session := range []*http.Cookie{host, guest} {
+		game := sessionGame(t, db, session)
+		left, _ := db.GetUnansweredQuestions(game.Id)
+		if game.CurrentQuestionId != room.CurrentQuestionId || len(left) != len(unanswered)-1 {
+			t.Fatal(game, len(left), len(unanswered))
+		}
+	}
+}
+
+func TestRooms_Events(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase(), Hub: NewHub()}
+
+	host, _ := openTestRoom(t, handlerContext)
+	code := sessionGame(t, handlerContext.DB, host).RoomCode
+
+	server := httptest.NewServer(http.HandlerFunc(handlerContext.Rooms))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/rooms/"+code+"/events/", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatal(resp.Header)
+	}
+
+	var stream strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && scanner.Text() != "" {
+		stream.WriteString(scanner.Text() + "\n")
+	}
+
+	if !strings.HasPrefix(stream.String(), "event: scores\n") || !strings.Contains(stream.String(), "host (host)") || !strings.Contains(stream.String(), "guest") {
+		t.Fatal(stream.String())
+	}
+}
+
+// hasEvent reports whether an event with the given name is waiting.
+func hasEvent(events <-chan Event, name string) bool {
+	for {
+		select {
+		case event := <-events:
+			if event.Name == name {
+				return true
+			}
+		default:
+			return false
+		}
+	}
+}
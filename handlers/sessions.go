@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"me885/fintech-or-furniture/quiz"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const gameCookieName = "gameSession"
+
+// setCookie sets a cookie that scripts cannot read, for the whole site. It is
+// only sent back over HTTPS when the server asks for secure cookies or is
+// serving HTTPS itself.
+func (context Context) setCookie(writer http.ResponseWriter, request *http.Request, cookie *http.Cookie) {
+	cookie.Path = "/"
+	cookie.HttpOnly = true
+	cookie.SameSite = http.SameSiteLaxMode
+	cookie.Secure = context.SecureCookies || request.TLS != nil
+
+	http.SetCookie(writer, cookie)
+}
+
+// gameSession returns the request's game session, or nil when it has none
+// or it has expired.
+func (context Context) gameSession(request *http.Request) (*quiz.GameSession, error) {
+	cookie, err := request.Cookie(gameCookieName)
+	if err != nil {
+		return nil, nil
+	}
+
+	session, err := context.DB.GetGameSession(cookie.Value)
+	if errors.Is(err, quiz.ErrNotExists) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if session.IsExpired(time.Now()) {
+		context.DB.DeleteGameSession(session.Token)
+		return nil, nil
+	}
+
+	return session, nil
+}
+
+// claimGameSession returns the game session a new game is to belong to: the
+// request's session, or a new one if it has none. The client only hears of
+// it from renewGameSession, once the game has been created.
+func (context Context) claimGameSession(request *http.Request) (*quiz.GameSession, error) {
+	session, err := context.gameSession(request)
+	if err != nil || session != nil {
+		return session, err
+	}
+
+	return context.DB.CreateGameSession(time.Now().Add(context.gameSessionLifetime()))
+}
+
+// renewGameSession is called once a game has been created in the session. It
+// gives the session a new token and sets the cookie. The new game belongs to
+// the session alongside its earlier games.
+func (context Context) renewGameSession(writer http.ResponseWriter, request *http.Request, session *quiz.GameSession) error {
+	session, err := context.DB.RotateGameSession(session.Id, time.Now().Add(context.gameSessionLifetime()))
+	if err != nil {
+		return err
+	}
+
+	context.setCookie(writer, request, &http.Cookie{Name: gameCookieName, Value: session.Token, Expires: session.Expires})
+
+	return nil
+}
+
+func (context Context) gameSessionLifetime() time.Duration {
+	if context.GameSessionLifetime == 0 {
+		return quiz.GameSessionLifetime
+	}
+	return context.GameSessionLifetime
+}
+
+// getGameIfAuthed returns the game the request's session is playing: its
+// newest game, or the one named by the "game" query parameter, which must
+// belong to the session.
+func getGameIfAuthed(request *http.Request, db quiz.Repository) (*quiz.Game, error) {
+	cookie, err := request.Cookie(gameCookieName)
+	if err != nil {
+		return nil, errors.New("gameSession cookie required")
+	}
+
+	session, err := db.GetGameSession(cookie.Value)
+	if err != nil || session.IsExpired(time.Now()) {
+		return nil, errors.New("gameSession not found or expired")
+	}
+
+	gameId := uuid.Nil
+	if id := request.URL.Query().Get("game"); id != "" {
+		if gameId, err = uuid.Parse(id); err != nil {
+			return nil, errors.New("game not found in session")
+		}
+	}
+
+	game, err := db.GetSessionGame(session.Id, gameId)
+	if errors.Is(err, quiz.ErrNotExists) {
+		return nil, errors.New("game not found in session")
+	}
+	return game, err
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"me885/fintech-or-furniture/quiz"
+	"me885/fintech-or-furniture/quiz/database"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// sessionCookie puts game in a new game session and returns its cookie.
+func sessionCookie(t *testing.T, db quiz.Repository, game *quiz.Game) *http.Cookie {
+	session, err := db.CreateGameSession(time.Now().Add(quiz.GameSessionLifetime))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game.SessionId = session.Id
+	if _, err := db.UpdateGame(game); err != nil {
+		t.Fatal(err)
+	}
+
+	return &http.Cookie{Name: gameCookieName, Value: session.Token}
+}
+
+func TestNewGame_SessionOwnsGames(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+
+	resp := formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"alice"}}, nil)
+	first := responseSession(t, resp)
+	firstGame := sessionGame(t, handlerContext.DB, first)
+
+	if first.Value == firstGame.Id.String() || !first.HttpOnly || first.Secure || first.Expires.IsZero() {
+		t.Fatal(first)
+	}
+
+	resp = formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"alice"}, "mode": {"quick"}}, first)
+	second := responseSession(t, resp)
+	secondGame := sessionGame(t, handlerContext.DB, second)
+
+	if second.Value == first.Value || secondGame.Id == firstGame.Id || secondGame.SessionId != firstGame.SessionId {
+		t.Fatal("a new game should rotate the token and keep the session", first, second)
+	}
+
+	// The old token stops working once rotated.
+	resp = formRequest(handlerContext.NextQuestion, "GET", "/next-question/", nil, first)
+	if resp.Code != http.StatusUnauthorized {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	// Earlier games of the session can still be reached by id.
+	for target, want := range map[string]*quiz.Game{"/api/v1/result/?game=" + firstGame.Id.String(): firstGame, "/api/v1/result/": secondGame} {
+		resp = formRequest(handlerContext.APIResult, "GET", target, nil, second)
+
+		var game quiz.Game
+		if err := json.NewDecoder(resp.Body).Decode(&game); err != nil || game.Id != want.Id {
+			t.Fatal(target, resp.Code, game, err)
+		}
+	}
+
+	// But other sessions' games cannot.
+	resp = formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"bob"}}, nil)
+	other := responseSession(t, resp)
+
+	resp = formRequest(handlerContext.APIResult, "GET", "/api/v1/result/?game="+firstGame.Id.String(), nil, other)
+	if resp.Code != http.StatusUnauthorized {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+}
+
+func TestNewGame_FailedStartKeepsSession(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+
+	resp := formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"alice"}}, nil)
+	session := responseSession(t, resp)
+	game := sessionGame(t, handlerContext.DB, session)
+
+	for _, form := range []url.Values{
+		{"name": {"alice"}, "mode": {"nonsense"}},
+		{"name": {"alice"}, "pack": {"nonsense"}},
+	} {
+		resp = formRequest(handlerContext.NewGame, "POST", "/new-game/", form, session)
+		if resp.Code != http.StatusBadRequest || len(resp.Result().Cookies()) != 0 {
+			t.Fatal(form, resp.Code, resp.Result().Cookies())
+		}
+	}
+
+	if still := sessionGame(t, handlerContext.DB, session); still.Id != game.Id {
+		t.Fatal("the session should still be playing its game", still)
+	}
+
+	if games, _ := handlerContext.DB.AllGames(); len(games) != 1 {
+		t.Fatal(games)
+	}
+}
+
+func TestGameSession_Expired(t *testing.T) {
+	testDb := database.InitMemoryDatabase()
+	handlerContext := Context{DB: testDb}
+
+	game, _ := testDb.CreateGame(quiz.NewGame{PlayerName: "alice", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+	session, _ := testDb.CreateGameSession(time.Now().Add(-time.Minute))
+	game.SessionId = session.Id
+	testDb.UpdateGame(game)
+
+	cookie := &http.Cookie{Name: gameCookieName, Value: session.Token}
+
+	resp := formRequest(handlerContext.NextQuestion, "GET", "/next-question/", nil, cookie)
+	if resp.Code != http.StatusUnauthorized {
+		t.Fatal(resp.Code, resp.Body.String())
+	}
+
+	// Starting a game with an expired session starts a fresh one.
+	resp = formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"alice"}}, cookie)
+	if renewed := sessionGame(t, testDb, responseSession(t, resp)); renewed.SessionId == session.Id {
+		t.Fatal(renewed)
+	}
+}
+
+func TestSetCookie_Secure(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase(), SecureCookies: true}
+
+	resp := formRequest(handlerContext.NewGame, "POST", "/new-game/", url.Values{"name": {"alice"}}, nil)
+	if cookie := responseSession(t, resp); !cookie.Secure {
+		t.Fatal(cookie)
+	}
+}
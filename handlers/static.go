@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// contentSecurityPolicy only lets pages load what this server serves. Images
+// may also be data URLs, which Bootstrap draws form controls with.
+const contentSecurityPolicy = "default-src 'self'; img-src 'self' data:; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+
+// WithContentSecurityPolicy sets contentSecurityPolicy on every response.
+func WithContentSecurityPolicy(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+		writer.Header().Set("X-Content-Type-Options", "nosniff")
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// StaticFiles serves files under /static/. Each file can also be fetched
+// with a hash of its contents in its name, which browsers may cache for
+// good since the name changes whenever the file does.
+type StaticFiles struct {
+	files  fs.FS
+	server http.Handler
+	// hashed maps each file's name to its name with the hash in, and
+	// original maps back.
+	hashed   map[string]string
+	original map[string]string
+}
+
+// NewStaticFiles hashes every file in files.
+func NewStaticFiles(files fs.FS) (*StaticFiles, error) {
+	static := &StaticFiles{files: files, server: http.FileServer(http.FS(files)), hashed: map[string]string{}, original: map[string]string{}}
+
+	err := fs.WalkDir(files, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		contents, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(contents)
+		extension := path.Ext(name)
+		hashed := strings.TrimSuffix(name, extension) + "." + hex.EncodeToString(sum[:5]) + extension
+
+		static.hashed[name] = hashed
+		static.original[hashed] = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return static, nil
+}
+
+// URL returns the URL to link to the named file with, with its hash if it
+// exists.
+func (static *StaticFiles) URL(name string) string {
+	if hashed, ok := static.hashed[name]; ok {
+		return "/static/" + hashed
+	}
+	return "/static/" + name
+}
+
+func (static *StaticFiles) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	name := strings.TrimPrefix(request.URL.Path, "/static/")
+
+	if original, ok := static.original[name]; ok {
+		writer.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		name = original
+	} else if _, ok := static.hashed[name]; ok {
+		writer.Header().Set("Cache-Control", "no-cache")
+	} else {
+		// Directories are not listed.
+		http.NotFound(writer, request)
+		return
+	}
+
+	request = request.Clone(request.Context())
+	request.URL.Path = "/" + name
+	static.server.ServeHTTP(writer, request)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"me885/fintech-or-furniture/quiz/database"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestStaticFiles(t *testing.T) *StaticFiles {
+	static, err := NewStaticFiles(fstest.MapFS{
+		"index.css":      &fstest.MapFile{Data: []byte("body {}")},
+		"vendor/lib.js":  &fstest.MapFile{Data: []byte("lib()")},
+		"vendor/lib2.js": &fstest.MapFile{Data: []byte("lib2()")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return static
+}
+
+func TestStaticFiles_URL(t *testing.T) {
+	static := newTestStaticFiles(t)
+
+	url := static.URL("vendor/lib.js")
+	if !strings.HasPrefix(url, "/static/vendor/lib.") || !strings.HasSuffix(url, ".js") || url == "/static/vendor/lib.js" {
+		t.Fatal(url)
+	}
+
+	if static.URL("vendor/lib2.js") == strings.Replace(url, "lib", "lib2", 1) {
+		t.Fatal("files with different contents should get different hashes")
+	}
+
+	if url := static.URL("missing.js"); url != "/static/missing.js" {
+		t.Fatal(url)
+	}
+}
+
+func TestStaticFiles_Serve(t *testing.T) {
+	static := newTestStaticFiles(t)
+
+	for _, test := range []struct {
+		path         string
+		status       int
+		cacheControl string
+	}{
+		{static.URL("index.css"), http.StatusOK, "public, max-age=31536000, immutable"},
+		{"/static/index.css", http.StatusOK, "no-cache"},
+		{"/static/vendor/", http.StatusNotFound, ""},
+		{"/static/missing.css", http.StatusNotFound, ""},
+	} {
+		req, _ := http.NewRequest("GET", test.path, nil)
+		resp := httptest.NewRecorder()
+		static.ServeHTTP(resp, req)
+
+		if resp.Code != test.status || resp.Header().Get("Cache-Control") != test.cacheControl {
+			t.Fatal(test.path, resp.Code, resp.Header())
+		}
+		if test.status == http.StatusOK && resp.Body.String() != "body {}" {
+			t.Fatal(test.path, resp.Body.String())
+		}
+	}
+}
+
+func TestWithContentSecurityPolicy(t *testing.T) {
+	handlerContext := Context{DB: database.InitMemoryDatabase()}
+	handler := WithContentSecurityPolicy(http.HandlerFunc(handlerContext.RootPage))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	if !strings.HasPrefix(resp.Header().Get("Content-Security-Policy"), "default-src 'self'") {
+		t.Fatal(resp.Header())
+	}
+
+	// Everything the page loads has to come from the server itself.
+	html := resp.Body.String()
+	if strings.Contains(html, "https://") || strings.Contains(html, "<style") || strings.Contains(html, "style=") {
+		t.Fatal(html)
+	}
+	if !strings.Contains(html, `href="/static/index.`) || strings.Contains(html, `href="/static/index.css"`) {
+		t.Fatal(html)
+	}
+}
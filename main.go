@@ -1,33 +1,249 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"me885/fintech-or-furniture/assets"
+	"me885/fintech-or-furniture/config"
 	"me885/fintech-or-furniture/handlers"
+	"me885/fintech-or-furniture/quiz"
 	"me885/fintech-or-furniture/quiz/database"
+	"me885/fintech-or-furniture/quiz/questionbank"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
+// Server timeouts. Event streams clear the read and write deadlines for
+// themselves, as they stay open for as long as a page does.
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 15 * time.Second
+	writeTimeout      = 30 * time.Second
+	idleTimeout       = 2 * time.Minute
+	// shutdownTimeout is how long requests in flight get to finish once the
+	// server is told to stop.
+	shutdownTimeout = 20 * time.Second
+)
+
 func main() {
 
-	for range time.Tick(time.Second * 10) {
-		fmt.Println("HEllo")
+	cfg, args, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(args) > 0 {
+		runCommand(cfg.DatabaseURL, args[0], args[1:])
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg, nil); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run serves until ctx is done, then stops taking requests, gives those in
+// flight, such as answers being scored, shutdownTimeout to finish and closes
+// the database. listening, if given, is called with the address once the
+// server is listening.
+func run(ctx context.Context, cfg *config.Config, listening func(net.Addr)) error {
+	if err := assets.CheckVendored(assets.Static(cfg.AssetsDir)); err != nil {
+		return err
+	}
+
+	static, err := handlers.NewStaticFiles(assets.Static(cfg.AssetsDir))
+	if err != nil {
+		return err
+	}
+
+	templates, err := handlers.NewRenderer(assets.Templates(cfg.AssetsDir), static, cfg.TemplateReload)
+	if err != nil {
+		return err
+	}
+
+	selector, err := quiz.ParseSelector(cfg.Selector)
+	if err != nil {
+		return err
+	}
+
+	modes, err := quiz.GameModes.WithQuestionCounts(cfg.QuestionCounts)
+	if err != nil {
+		return err
+	}
+
+	db := database.InitRepository(cfg.DatabaseURL)
+	handlersContext := &handlers.Context{
+		DB:                    db,
+		Selector:              selector,
+		Modes:                 modes,
+		Templates:             templates,
+		Admins:                handlers.ParseAdmins(cfg.AdminUsers),
+		Hub:                   handlers.NewHub(),
+		Bus:                   handlers.NewBus(),
+		SecureCookies:         cfg.SecureCookies,
+		GameSessionLifetime:   time.Duration(cfg.GameSessionLifetime),
+		PlayerSessionLifetime: time.Duration(cfg.PlayerSessionLifetime),
+	}
+	handlersContext.Bus.OnGameCompleted(handlersContext.PushLeaderboard)
+
+	server := &http.Server{
+		Handler:           handlers.WithContentSecurityPolicy(routes(handlersContext, static)),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	server.RegisterOnShutdown(handlersContext.Hub.Close)
+
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	log.Printf("Now running on %s", listener.Addr())
+	if listening != nil {
+		listening(listener.Addr())
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	select {
+	case err := <-served:
+		db.Close()
+		return err
+	case <-ctx.Done():
 	}
 
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	log.Print("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = server.Shutdown(shutdownCtx)
+	<-served
+
+	return errors.Join(err, db.Close())
+}
+
+// routes registers every page, endpoint and the static files.
+func routes(handlersContext *handlers.Context, static *handlers.StaticFiles) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.Handle("/static/", static)
+	mux.HandleFunc("/healthz", handlersContext.Healthy)
+	mux.HandleFunc("/readyz", handlersContext.Ready)
+
+	mux.HandleFunc("/", handlersContext.RootPage)
+	mux.HandleFunc("/new-game/", handlersContext.NewGame)
+	mux.HandleFunc("/answer/", handlersContext.Answer)
+	mux.HandleFunc("/next-question/", handlersContext.NextQuestion)
+	mux.HandleFunc("/leaderboard/", handlersContext.Leaderboard)
+	mux.HandleFunc("/leaderboard/daily/", handlersContext.DailyLeaderboard)
+	mux.HandleFunc("/leaderboard/events/", handlersContext.LeaderboardEvents)
+	mux.HandleFunc("/leaderboard-content/", handlersContext.LeaderboardTable)
+	mux.HandleFunc("/result/", handlersContext.EndPage)
+	mux.HandleFunc("/rooms/", handlersContext.Rooms)
+	mux.HandleFunc("/account/", handlersContext.Account)
+	mux.HandleFunc("/players/", handlersContext.Profile)
+
+	mux.HandleFunc("/admin/", handlersContext.AdminPage)
+	mux.HandleFunc("/admin/questions/", handlersContext.AdminQuestions)
+	mux.HandleFunc("/admin/audit/", handlersContext.AdminAudit)
+
+	mux.HandleFunc("/api/v1/games/", handlersContext.APINewGame)
+	mux.HandleFunc("/api/v1/question/", handlersContext.APIQuestion)
+	mux.HandleFunc("/api/v1/answer/", handlersContext.APIAnswer)
+	mux.HandleFunc("/api/v1/result/", handlersContext.APIResult)
+	mux.HandleFunc("/api/v1/leaderboard/", handlersContext.APILeaderboard)
+	mux.HandleFunc("/api/v1/leaderboard/daily/", handlersContext.APIDailyLeaderboard)
 
-	db := database.InitDatabase("sqlite.db")
-	handlersContext := &handlers.Context{DB: db}
+	return mux
+}
+
+// runCommand handles the maintenance subcommands that run instead of the
+// server.
+func runCommand(dsn string, command string, args []string) {
+	switch command {
+	case "migrate":
+		if err := database.MigrateDatabase(dsn); err != nil {
+			log.Fatal(err)
+		}
+		log.Print("Database schema is up to date")
+
+	case "import":
+		if len(args) != 1 {
+			log.Fatal("usage: import <questions.csv|questions.json>")
+		}
+
+		format, err := questionbank.FormatFromFilename(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+
+		report, err := questionbank.Import(database.OpenRepository(dsn), file, format)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Print(report)
+
+	case "import-packs":
+		if len(args) != 1 {
+			log.Fatal("usage: import-packs <packs.json>")
+		}
 
-	http.HandleFunc("/", handlers.RootPage)
-	http.HandleFunc("/new-game/", handlersContext.NewGame)
-	http.HandleFunc("/answer/", handlersContext.Answer)
-	http.HandleFunc("/next-question/", handlersContext.NextQuestion)
-	http.HandleFunc("/leaderboard/", handlersContext.Leaderboard)
-	http.HandleFunc("/leaderboard-content/", handlersContext.LeaderboardTable)
-	http.HandleFunc("/result/", handlersContext.EndPage)
+		file, err := os.Open(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
 
-	log.Print("Now running on http://localhost:8002")
-	log.Fatal(http.ListenAndServe(":8002", nil))
+		report, err := questionbank.ImportPacks(database.OpenRepository(dsn), file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Print(report)
+
+	case "export":
+		if len(args) != 1 {
+			log.Fatal("usage: export <questions.csv|questions.json>")
+		}
+
+		format, err := questionbank.FormatFromFilename(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		file, err := os.Create(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+
+		if err := questionbank.Export(database.OpenRepository(dsn), file, format); err != nil {
+			log.Fatal(err)
+		}
+
+	default:
+		log.Fatalf("unknown command %q, expected migrate, import, import-packs or export", command)
+	}
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"io"
+	"me885/fintech-or-furniture/assets"
+	"me885/fintech-or-furniture/config"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRun_ServesAndShutsDown(t *testing.T) {
+	cfg := config.Default()
+	cfg.Addr = "127.0.0.1:0"
+	cfg.DatabaseURL = filepath.Join(t.TempDir(), "test.db")
+	cfg.AssetsDir = vendoredStubs(t)
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	addrs := make(chan net.Addr, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- run(ctx, &cfg, func(addr net.Addr) { addrs <- addr })
+	}()
+
+	var base string
+	select {
+	case addr := <-addrs:
+		base = "http://" + addr.String()
+	case err := <-done:
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(path, resp.StatusCode)
+		}
+	}
+
+	// An open event stream should end with the server rather than hold up
+	// its shutdown.
+	stream, err := http.Get(base + "/leaderboard/events/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+
+	if stream.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatal(stream.Status, stream.Header)
+	}
+
+	stop()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(shutdownTimeout / 2):
+		t.Fatal("the server took too long to shut down")
+	}
+
+	// The stream should have been finished properly rather than cut off.
+	if _, err := io.ReadAll(stream.Body); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := http.Get(base + "/healthz"); err == nil {
+		t.Fatal("the server should have stopped listening")
+	}
+}
+
+// vendoredStubs returns an assets directory with stand-ins for the vendored
+// files, so that the server starts whether or not they have been fetched.
+func vendoredStubs(t *testing.T) string {
+	dir := t.TempDir()
+	for _, name := range assets.Vendored {
+		file := filepath.Join(dir, "static", name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("/* stub */"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,43 @@
+package quiz
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// DailyMode is the daily challenge: everyone playing it on the same day is
+// asked the same questions from the default pack, and each player name gets
+// one go a day.
+const DailyMode = "daily"
+
+const challengeDayLayout = "2006-01-02"
+
+// ChallengeDay is the daily challenge being played at now. Days run midnight
+// to midnight UTC.
+func ChallengeDay(now time.Time) string {
+	return now.UTC().Format(challengeDayLayout)
+}
+
+// ParseChallengeDay checks a day such as "2026-10-18", defaulting to today's
+// challenge when it is empty.
+func ParseChallengeDay(day string, now time.Time) (string, error) {
+	if day == "" {
+		return ChallengeDay(now), nil
+	}
+
+	if _, err := time.Parse(challengeDayLayout, day); err != nil {
+		return "", fmt.Errorf("day should look like %s", challengeDayLayout)
+	}
+	return day, nil
+}
+
+// DailySeed is the seed every game of a day's challenge shares, so that with
+// a selector that ignores the player's answers they are all asked the same
+// questions.
+func DailySeed(day string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte("daily challenge " + day))
+
+	return int64(hash.Sum64())
+}
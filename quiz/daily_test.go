@@ -0,0 +1,40 @@
+package quiz
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChallengeDay_IsUTC(t *testing.T) {
+	now := time.Date(2026, 10, 18, 23, 30, 0, 0, time.FixedZone("", -2*60*60))
+
+	if day := ChallengeDay(now); day != "2026-10-19" {
+		t.Fatal(day)
+	}
+}
+
+func TestParseChallengeDay(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	if day, err := ParseChallengeDay("", now); err != nil || day != "2026-10-18" {
+		t.Fatal(day, err)
+	}
+
+	if day, err := ParseChallengeDay("2026-10-01", now); err != nil || day != "2026-10-01" {
+		t.Fatal(day, err)
+	}
+
+	if _, err := ParseChallengeDay("yesterday", now); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestDailySeed(t *testing.T) {
+	if DailySeed("2026-10-18") != DailySeed("2026-10-18") {
+		t.Fatal("the same day should have the same seed")
+	}
+
+	if DailySeed("2026-10-18") == DailySeed("2026-10-19") {
+		t.Fatal("each day should have its own seed")
+	}
+}
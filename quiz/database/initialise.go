@@ -1,12 +1,70 @@
 package database
 
 import (
+	"bytes"
 	"database/sql"
+	_ "embed"
 	"log"
 	"me885/fintech-or-furniture/quiz"
+	"me885/fintech-or-furniture/quiz/questionbank"
+	"strings"
 )
 
+// InitRepository picks the storage backend from the DSN, brings its schema up
+// to date and seeds the default questions into an empty bank.
+func InitRepository(dsn string) quiz.Repository {
+	repository := OpenRepository(dsn)
+
+	SeedQuestions(repository)
+
+	return repository
+}
+
+// OpenRepository is InitRepository without the seeding, for tools that work
+// on the bank as it is.
+func OpenRepository(dsn string) quiz.Repository {
+	if isPostgresDSN(dsn) {
+		return openPostgresDatabase(dsn)
+	}
+
+	return openDatabase(dsn)
+}
+
+// MigrateDatabase brings the schema of the database at dsn up to date without
+// seeding any questions.
+func MigrateDatabase(dsn string) error {
+	driver := "sqlite3"
+	if isPostgresDSN(dsn) {
+		driver = "postgres"
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if driver == "postgres" {
+		return NewPostgresRepository(db).Migrate()
+	}
+	return NewSQLiteRepository(db).Migrate()
+}
+
+// isPostgresDSN reports whether dsn is a postgres:// or postgresql:// URL;
+// anything else is treated as a SQLite filename.
+func isPostgresDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
 func InitDatabase(filename string) *SQLiteRepository {
+	sqliteRepository := openDatabase(filename)
+
+	SeedQuestions(sqliteRepository)
+
+	return sqliteRepository
+}
+
+func openDatabase(filename string) *SQLiteRepository {
 	db, err := sql.Open("sqlite3", filename)
 	if err != nil {
 		log.Fatal(err)
@@ -18,45 +76,66 @@ func InitDatabase(filename string) *SQLiteRepository {
 		log.Fatal(err)
 	}
 
-	questions := [...]quiz.Question{
-		{Question: "PAX", Answer: quiz.Furniture},
-		{Question: "YAVRIO", Answer: quiz.Fintech},
-		{Question: "YPPERLIG", Answer: quiz.Furniture},
-		{Question: "ZYNGA", Answer: quiz.Fintech},
-		{Question: "SLYP", Answer: quiz.Fintech},
-		{Question: "FADO", Answer: quiz.Furniture},
-		{Question: "LACK", Answer: quiz.Furniture},
-		{Question: "TROFAST", Answer: quiz.Furniture},
-		{Question: "ANROK", Answer: quiz.Fintech},
-		{Question: "VOXNAN", Answer: quiz.Furniture},
-		{Question: "VOWCH", Answer: quiz.Fintech},
-		{Question: "CRUX", Answer: quiz.Fintech},
-		{Question: "FYSSE", Answer: quiz.Furniture},
-		{Question: "STORI", Answer: quiz.Fintech},
-		{Question: "KALLAX", Answer: quiz.Furniture},
-		{Question: "PAGOS", Answer: quiz.Fintech},
-		{Question: "SPARSAM", Answer: quiz.Furniture},
-		{Question: "EXPEDIT", Answer: quiz.Furniture},
-		{Question: "SNIGLAR", Answer: quiz.Furniture},
-		{Question: "APA", Answer: quiz.Furniture},
-		{Question: "ANRIK", Answer: quiz.Furniture},
-		{Question: "CELEBER", Answer: quiz.Furniture},
-		{Question: "KOBALT", Answer: quiz.Fintech},
-		{Question: "BARK", Answer: quiz.Fintech},
-		{Question: "YASSIR", Answer: quiz.Fintech},
-		{Question: "ZILCH", Answer: quiz.Fintech},
-		{Question: "ACIN", Answer: quiz.Fintech},
-		{Question: "LEANIX", Answer: quiz.Fintech},
-		{Question: "TARVA", Answer: quiz.Furniture},
-		{Question: "ALEX", Answer: quiz.Furniture},
-		{Question: "FEJAN", Answer: quiz.Furniture},
-		{Question: "HYLLIS", Answer: quiz.Furniture},
-		{Question: "GALANT", Answer: quiz.Furniture},
-	}
-
-	for _, element := range questions {
-		sqliteRepository.CreateQuestion(element)
+	return sqliteRepository
+}
+
+func InitPostgresDatabase(dsn string) *PostgresRepository {
+	postgresRepository := openPostgresDatabase(dsn)
+
+	SeedQuestions(postgresRepository)
+
+	return postgresRepository
+}
+
+func openPostgresDatabase(dsn string) *PostgresRepository {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	return sqliteRepository
+	postgresRepository := NewPostgresRepository(db)
+
+	if err := postgresRepository.Migrate(); err != nil {
+		log.Fatal(err)
+	}
+
+	return postgresRepository
+}
+
+// InitMemoryDatabase returns an in-memory repository seeded with the same
+// questions as InitDatabase, for use where a file on disk is not wanted.
+func InitMemoryDatabase() *MemoryRepository {
+	memoryRepository := NewMemoryRepository()
+
+	SeedQuestions(memoryRepository)
+
+	return memoryRepository
+}
+
+//go:embed default-packs.json
+var defaultPacks []byte
+
+//go:embed default-questions.csv
+var defaultQuestions string
+
+// SeedQuestions adds the default packs, beyond the one the migrations create,
+// and imports their questions, but only into an empty bank. A bank with any
+// questions is left alone, so that questions deleted or renamed in the admin
+// console stay that way when the server restarts.
+func SeedQuestions(repository quiz.Repository) {
+	count, err := repository.CountQuestions()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if count > 0 {
+		return
+	}
+
+	if _, err := questionbank.ImportPacks(repository, bytes.NewReader(defaultPacks)); err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := questionbank.Import(repository, strings.NewReader(defaultQuestions), questionbank.CSV); err != nil {
+		log.Fatal(err)
+	}
 }
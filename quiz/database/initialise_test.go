@@ -0,0 +1,64 @@
+package database
+
+import (
+	"errors"
+	"me885/fintech-or-furniture/quiz"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitDatabase_SeedsOnlyOnce(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.db")
+
+	repo := InitDatabase(filename)
+
+	seeded, err := repo.AllQuestions()
+	if err != nil || len(seeded) < 2 {
+		t.Fatal(len(seeded), err)
+	}
+
+	deleted := seeded[0]
+	if err := repo.DeleteQuestion(deleted.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	renamed := seeded[1]
+	renamed.Question = "Renamed " + renamed.Question
+	if _, err := repo.UpdateQuestion(renamed); err != nil {
+		t.Fatal(err)
+	}
+	repo.Close()
+
+	repo = InitDatabase(filename)
+	defer repo.Close()
+
+	if count, _ := repo.CountQuestions(); count != int64(len(seeded)-1) {
+		t.Fatal("restarting should not seed the bank again", count, len(seeded))
+	}
+
+	if _, err := repo.GetQuestionByText(deleted.Question); !errors.Is(err, quiz.ErrNotExists) {
+		t.Fatal("a deleted question should stay deleted", err)
+	}
+
+	if _, err := repo.GetQuestionByText(seeded[1].Question); !errors.Is(err, quiz.ErrNotExists) {
+		t.Fatal("a renamed question should not come back under its old name", err)
+	}
+}
+
+func TestSeedQuestions_FillEveryMode(t *testing.T) {
+	repo := InitMemoryDatabase()
+
+	packs, err := repo.AllPacks()
+	if err != nil || len(packs) < 2 {
+		t.Fatal(packs, err)
+	}
+
+	for _, pack := range packs {
+		available, _ := repo.CountEnabledQuestions(pack.Id)
+		for _, mode := range quiz.GameModes {
+			if err := quiz.CheckBankSize(mode, available); err != nil {
+				t.Error(pack.Slug, err)
+			}
+		}
+	}
+}
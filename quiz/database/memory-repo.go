@@ -0,0 +1,809 @@
+package database
+
+import (
+	"context"
+	"math/rand"
+	"me885/fintech-or-furniture/quiz"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryRepository keeps everything in maps guarded by a mutex. It behaves
+// like SQLiteRepository but is lost when the process exits, which makes it
+// handy for tests.
+type MemoryRepository struct {
+	mu            sync.Mutex
+	packs         []quiz.Pack
+	questions     []quiz.Question
+	games         map[uuid.UUID]quiz.Game
+	gameQuestions map[uuid.UUID]map[int64]bool
+	// gameStats holds each game's snapshot of its questions' statistics.
+	gameStats      map[uuid.UUID]map[int64]quiz.Question
+	rooms          map[string]quiz.Room
+	players        []quiz.Player
+	sessions       map[string]quiz.Session
+	gameSessions   []quiz.GameSession
+	auditEntries   []quiz.AuditEntry
+	nextQuestionId int64
+	nextOptionId   quiz.Answer
+	closed         bool
+}
+
+var _ quiz.Repository = (*MemoryRepository)(nil)
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		packs:          []quiz.Pack{quiz.DefaultPack},
+		games:          map[uuid.UUID]quiz.Game{},
+		gameQuestions:  map[uuid.UUID]map[int64]bool{},
+		gameStats:      map[uuid.UUID]map[int64]quiz.Question{},
+		rooms:          map[string]quiz.Room{},
+		sessions:       map[string]quiz.Session{},
+		nextQuestionId: 1,
+		nextOptionId:   quiz.Furniture + 1,
+	}
+}
+
+// Ping only fails once the repository is closed, since there is nothing to
+// reach.
+func (r *MemoryRepository) Ping(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return quiz.ErrClosed
+	}
+	return ctx.Err()
+}
+
+func (r *MemoryRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	return nil
+}
+
+func (r *MemoryRepository) CreatePack(pack quiz.Pack) (*quiz.Pack, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.packs {
+		if existing.Slug == pack.Slug {
+			return nil, ErrDuplicate
+		}
+	}
+
+	pack.Id = r.packs[len(r.packs)-1].Id + 1
+
+	pack.Options = append([]quiz.AnswerOption(nil), pack.Options...)
+	for i := range pack.Options {
+		for _, other := range pack.Options[:i] {
+			if other.Label == pack.Options[i].Label {
+				return nil, ErrDuplicate
+			}
+		}
+
+		pack.Options[i].Id = r.nextOptionId
+		r.nextOptionId++
+	}
+
+	r.packs = append(r.packs, pack)
+
+	return &pack, nil
+}
+
+func (r *MemoryRepository) GetPackById(id int64) (*quiz.Pack, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, pack := range r.packs {
+		if pack.Id == id {
+			return &pack, nil
+		}
+	}
+	return nil, ErrNotExists
+}
+
+func (r *MemoryRepository) GetPackBySlug(slug string) (*quiz.Pack, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, pack := range r.packs {
+		if pack.Slug == slug {
+			return &pack, nil
+		}
+	}
+	return nil, ErrNotExists
+}
+
+func (r *MemoryRepository) AllPacks() ([]quiz.Pack, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]quiz.Pack(nil), r.packs...), nil
+}
+
+func (r *MemoryRepository) UpdatePack(pack quiz.Pack) (*quiz.Pack, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.packs {
+		if existing.Id != pack.Id {
+			continue
+		}
+
+		options := append([]quiz.AnswerOption(nil), existing.Options...)
+		nextOptionId := r.nextOptionId
+		for _, option := range pack.Options {
+			if option.Id != 0 {
+				continue
+			}
+
+			for _, other := range options {
+				if other.Label == option.Label {
+					return nil, ErrDuplicate
+				}
+			}
+
+			option.Id = nextOptionId
+			nextOptionId++
+			options = append(options, option)
+		}
+
+		r.nextOptionId = nextOptionId
+
+		existing.Name = pack.Name
+		existing.Options = options
+		r.packs[i] = existing
+
+		return &existing, nil
+	}
+
+	return nil, ErrNotExists
+}
+
+func (r *MemoryRepository) AddGameQuestion(gameId uuid.UUID, questionId int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.gameQuestions[gameId] == nil {
+		r.gameQuestions[gameId] = map[int64]bool{}
+	}
+	r.gameQuestions[gameId][questionId] = true
+
+	return nil
+}
+
+func (r *MemoryRepository) ClaimCurrentQuestion(gameId uuid.UUID, questionId int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	game, ok := r.games[gameId]
+	if !ok || game.CurrentQuestionId == 0 || game.CurrentQuestionId != questionId {
+		return ErrUpdateFailed
+	}
+
+	game.CurrentQuestionId = 0
+	r.games[gameId] = game
+
+	return nil
+}
+
+func (r *MemoryRepository) RemoveGameQuestions(gameId uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.gameQuestions, gameId)
+
+	return nil
+}
+
+func (r *MemoryRepository) GetUnansweredQuestions(gameId uuid.UUID) ([]quiz.Question, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	packId := r.games[gameId].PackId
+
+	var all []quiz.Question
+	for _, question := range r.questions {
+		if !question.Disabled && question.PackId == packId && !r.gameQuestions[gameId][question.Id] {
+			snapshot := r.gameStats[gameId][question.Id]
+			question.TimesServed = snapshot.TimesServed
+			question.TimesCorrect = snapshot.TimesCorrect
+			all = append(all, question)
+		}
+	}
+	return all, nil
+}
+
+func (r *MemoryRepository) CreateQuestion(question quiz.Question) (*quiz.Question, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.createQuestion(question)
+}
+
+func (r *MemoryRepository) createQuestion(question quiz.Question) (*quiz.Question, error) {
+	for _, existing := range r.questions {
+		if existing.Question == question.Question {
+			return nil, ErrDuplicate
+		}
+	}
+
+	if question.PackId == 0 {
+		question.PackId = quiz.DefaultPackId
+	}
+
+	// Like the SQL repositories, new questions start without statistics.
+	question.TimesServed = 0
+	question.TimesCorrect = 0
+
+	question.Id = r.nextQuestionId
+	r.nextQuestionId++
+	r.questions = append(r.questions, question)
+
+	return &question, nil
+}
+
+func (r *MemoryRepository) GetQuestionById(id int64) (*quiz.Question, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, question := range r.questions {
+		if question.Id == id {
+			return &question, nil
+		}
+	}
+	return nil, ErrNotExists
+}
+
+func (r *MemoryRepository) GetQuestionByText(text string) (*quiz.Question, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, question := range r.questions {
+		if question.Question == text {
+			return &question, nil
+		}
+	}
+	return nil, ErrNotExists
+}
+
+func (r *MemoryRepository) UpdateQuestion(question quiz.Question) (*quiz.Question, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.updateQuestion(question)
+}
+
+func (r *MemoryRepository) updateQuestion(question quiz.Question) (*quiz.Question, error) {
+	index := -1
+	for i, existing := range r.questions {
+		if existing.Id == question.Id {
+			index = i
+		} else if existing.Question == question.Question {
+			return nil, ErrDuplicate
+		}
+	}
+
+	if index == -1 {
+		return nil, ErrUpdateFailed
+	}
+
+	question.TimesServed = r.questions[index].TimesServed
+	question.TimesCorrect = r.questions[index].TimesCorrect
+	r.questions[index] = question
+
+	return &question, nil
+}
+
+func (r *MemoryRepository) RecordAnswer(questionId int64, correct bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.questions {
+		if r.questions[i].Id == questionId {
+			r.questions[i].TimesServed++
+			if correct {
+				r.questions[i].TimesCorrect++
+			}
+			return nil
+		}
+	}
+
+	return ErrUpdateFailed
+}
+
+func (r *MemoryRepository) AllQuestions() ([]quiz.Question, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]quiz.Question(nil), r.questions...), nil
+}
+
+func (r *MemoryRepository) ListQuestions(search string, offset int, limit int) ([]quiz.Question, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	search = strings.ToLower(search)
+
+	var matching []quiz.Question
+	for _, question := range r.questions {
+		if strings.Contains(strings.ToLower(question.Question), search) ||
+			strings.Contains(strings.ToLower(question.Source), search) ||
+			strings.Contains(strings.ToLower(question.Notes), search) {
+			matching = append(matching, question)
+		}
+	}
+
+	total := int64(len(matching))
+
+	if offset > len(matching) {
+		offset = len(matching)
+	}
+	matching = matching[offset:]
+
+	if limit < len(matching) {
+		matching = matching[:limit]
+	}
+
+	return matching, total, nil
+}
+
+func (r *MemoryRepository) DeleteQuestion(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.deleteQuestion(id)
+}
+
+func (r *MemoryRepository) deleteQuestion(id int64) error {
+	for i, question := range r.questions {
+		if question.Id == id {
+			r.questions = append(r.questions[:i], r.questions[i+1:]...)
+			return nil
+		}
+	}
+	return ErrDeleteFailed
+}
+
+func (r *MemoryRepository) AddAuditEntry(entry quiz.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.addAuditEntry(entry)
+}
+
+func (r *MemoryRepository) addAuditEntry(entry quiz.AuditEntry) error {
+	entry.Id = int64(len(r.auditEntries) + 1)
+	entry.At = entry.At.UTC()
+	r.auditEntries = append(r.auditEntries, entry)
+
+	return nil
+}
+
+func (r *MemoryRepository) CreateQuestionAudited(question quiz.Question, entry quiz.AuditEntry) (*quiz.Question, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created, err := r.createQuestion(question)
+	if err != nil {
+		return nil, err
+	}
+
+	return created, r.addAuditEntry(entry.For(*created))
+}
+
+func (r *MemoryRepository) UpdateQuestionAudited(question quiz.Question, entry quiz.AuditEntry) (*quiz.Question, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	updated, err := r.updateQuestion(question)
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, r.addAuditEntry(entry.For(*updated))
+}
+
+func (r *MemoryRepository) DeleteQuestionAudited(question quiz.Question, entry quiz.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.deleteQuestion(question.Id); err != nil {
+		return err
+	}
+
+	return r.addAuditEntry(entry.For(question))
+}
+
+func (r *MemoryRepository) AuditEntries(limit int) ([]quiz.AuditEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var all []quiz.AuditEntry
+	for i := len(r.auditEntries) - 1; i >= 0 && len(all) < limit; i-- {
+		all = append(all, r.auditEntries[i])
+	}
+	return all, nil
+}
+
+func (r *MemoryRepository) CountQuestions() (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return int64(len(r.questions)), nil
+}
+
+func (r *MemoryRepository) CountEnabledQuestions(packId int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, question := range r.questions {
+		if !question.Disabled && question.PackId == packId {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryRepository) CreatePlayer(username string, passwordHash string) (*quiz.Player, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.players {
+		if strings.EqualFold(existing.Username, username) {
+			return nil, ErrDuplicate
+		}
+	}
+
+	player := quiz.Player{Id: int64(len(r.players)) + 1, Username: username, PasswordHash: passwordHash, Created: time.Now().UTC()}
+
+	r.players = append(r.players, player)
+
+	return &player, nil
+}
+
+func (r *MemoryRepository) GetPlayerById(id int64) (*quiz.Player, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, player := range r.players {
+		if player.Id == id {
+			return &player, nil
+		}
+	}
+	return nil, ErrNotExists
+}
+
+func (r *MemoryRepository) GetPlayerByUsername(username string) (*quiz.Player, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, player := range r.players {
+		if strings.EqualFold(player.Username, username) {
+			return &player, nil
+		}
+	}
+	return nil, ErrNotExists
+}
+
+func (r *MemoryRepository) PlayerGames(playerId int64) ([]quiz.Game, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var all []quiz.Game
+	for _, game := range r.games {
+		if game.PlayerId == playerId {
+			all = append(all, game)
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].Created.After(all[j].Created) })
+
+	return all, nil
+}
+
+func (r *MemoryRepository) CreateSession(playerId int64, expires time.Time) (*quiz.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session := quiz.Session{Token: quiz.NewSessionToken(), PlayerId: playerId, Created: time.Now().UTC(), Expires: expires.UTC()}
+
+	r.sessions[session.Token] = session
+
+	return &session, nil
+}
+
+func (r *MemoryRepository) GetSession(token string) (*quiz.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[token]
+	if !ok {
+		return nil, ErrNotExists
+	}
+	return &session, nil
+}
+
+func (r *MemoryRepository) DeleteSession(token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sessions[token]; !ok {
+		return ErrDeleteFailed
+	}
+
+	delete(r.sessions, token)
+	return nil
+}
+
+func (r *MemoryRepository) CreateGameSession(expires time.Time) (*quiz.GameSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session := quiz.GameSession{Id: int64(len(r.gameSessions)) + 1, Token: quiz.NewSessionToken(), Created: time.Now().UTC(), Expires: expires.UTC()}
+
+	r.gameSessions = append(r.gameSessions, session)
+
+	return &session, nil
+}
+
+func (r *MemoryRepository) GetGameSession(token string) (*quiz.GameSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, session := range r.gameSessions {
+		if session.Token == token {
+			return &session, nil
+		}
+	}
+	return nil, ErrNotExists
+}
+
+func (r *MemoryRepository) RotateGameSession(id int64, expires time.Time) (*quiz.GameSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, session := range r.gameSessions {
+		if session.Id == id && session.Token != "" {
+			r.gameSessions[i].Token = quiz.NewSessionToken()
+			r.gameSessions[i].Expires = expires.UTC()
+			session = r.gameSessions[i]
+			return &session, nil
+		}
+	}
+	return nil, ErrNotExists
+}
+
+// DeleteGameSession blanks the session's token rather than removing it, so
+// that ids are not reused.
+func (r *MemoryRepository) DeleteGameSession(token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, session := range r.gameSessions {
+		if session.Token == token && token != "" {
+			r.gameSessions[i].Token = ""
+			return nil
+		}
+	}
+	return ErrDeleteFailed
+}
+
+func (r *MemoryRepository) GetSessionGame(sessionId int64, gameId uuid.UUID) (*quiz.Game, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var newest *quiz.Game
+	for _, game := range r.games {
+		if game.SessionId != sessionId || (gameId != uuid.Nil && game.Id != gameId) {
+			continue
+		}
+		if newest == nil || game.Created.After(newest.Created) {
+			found := game
+			newest = &found
+		}
+	}
+
+	if newest == nil {
+		return nil, ErrNotExists
+	}
+	return newest, nil
+}
+
+func (r *MemoryRepository) CreateGame(start quiz.NewGame) (*quiz.Game, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if start.RoomCode != "" {
+		room, ok := r.rooms[start.RoomCode]
+		if !ok {
+			return nil, ErrNotExists
+		}
+		if room.State != quiz.RoomLobby {
+			return nil, quiz.ErrRoomStarted
+		}
+	}
+
+	for _, existing := range r.games {
+		sameDay := start.ChallengeDay != "" && existing.ChallengeDay == start.ChallengeDay
+		sameRoom := start.RoomCode != "" && existing.RoomCode == start.RoomCode
+		if (sameDay || sameRoom) && strings.EqualFold(existing.PlayerName, start.PlayerName) {
+			return nil, ErrDuplicate
+		}
+	}
+
+	return r.insertGame(newGame(start, time.Now().UTC())), nil
+}
+
+// insertGame adds the game along with a snapshot of its pack's question
+// statistics, which its questions are picked with.
+func (r *MemoryRepository) insertGame(game quiz.Game) *quiz.Game {
+	r.games[game.Id] = game
+
+	stats := map[int64]quiz.Question{}
+	for _, question := range r.questions {
+		if question.PackId == game.PackId && question.TimesServed > 0 {
+			stats[question.Id] = question
+		}
+	}
+	r.gameStats[game.Id] = stats
+
+	return &game
+}
+
+func (r *MemoryRepository) GetGameById(id uuid.UUID) (*quiz.Game, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	game, ok := r.games[id]
+	if !ok {
+		return nil, ErrNotExists
+	}
+	return &game, nil
+}
+
+func (r *MemoryRepository) UpdateGame(game *quiz.Game) (*quiz.Game, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.games[game.Id]; !ok {
+		return nil, ErrUpdateFailed
+	}
+
+	r.games[game.Id] = *game
+
+	return game, nil
+}
+
+func (r *MemoryRepository) AllGames() ([]quiz.Game, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var all []quiz.Game
+	for _, game := range r.games {
+		all = append(all, game)
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].Created.Before(all[j].Created) })
+
+	return all, nil
+}
+
+func (r *MemoryRepository) CreateRoom(code string, packId int64, hostGameId uuid.UUID) (*quiz.Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rooms[code]; ok {
+		return nil, ErrDuplicate
+	}
+
+	room := quiz.Room{Code: code, PackId: packId, HostGameId: hostGameId, State: quiz.RoomLobby, Seed: rand.Int63(), Created: time.Now().UTC()}
+
+	r.rooms[code] = room
+
+	return &room, nil
+}
+
+func (r *MemoryRepository) GetRoomByCode(code string) (*quiz.Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	room, ok := r.rooms[code]
+	if !ok {
+		return nil, ErrNotExists
+	}
+	return &room, nil
+}
+
+func (r *MemoryRepository) UpdateRoom(room *quiz.Room, previous quiz.Room) (*quiz.Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.rooms[room.Code]
+	if !ok || existing.State != previous.State || existing.QuestionsAsked != previous.QuestionsAsked {
+		return nil, ErrUpdateFailed
+	}
+
+	existing.State = room.State
+	existing.CurrentQuestionId = room.CurrentQuestionId
+	existing.QuestionsAsked = room.QuestionsAsked
+	existing.QuestionServed = room.QuestionServed
+	r.rooms[room.Code] = existing
+
+	return room, nil
+}
+
+func (r *MemoryRepository) RoomGames(code string) ([]quiz.Game, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var all []quiz.Game
+	for _, game := range r.games {
+		if game.RoomCode == code {
+			all = append(all, game)
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].Created.Before(all[j].Created) })
+
+	return all, nil
+}
+
+func (r *MemoryRepository) TopTenCompletedGames(mode string, since time.Time) ([]quiz.Game, error) {
+	return r.topTenCompletedGames(mode, since, func(game quiz.Game) int64 { return game.Score })
+}
+
+func (r *MemoryRepository) TopTenStreaks(mode string, since time.Time) ([]quiz.Game, error) {
+	return r.topTenCompletedGames(mode, since, func(game quiz.Game) int64 { return game.BestStreak })
+}
+
+func (r *MemoryRepository) TopTenDailyGames(day string) ([]quiz.Game, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var all []quiz.Game
+	for _, game := range r.games {
+		if !game.InProgress && game.ChallengeDay == day {
+			all = append(all, game)
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Score != all[j].Score {
+			return all[i].Score > all[j].Score
+		}
+		return all[i].Completed.Before(all[j].Completed)
+	})
+
+	if len(all) > 10 {
+		all = all[:10]
+	}
+	return all, nil
+}
+
+func (r *MemoryRepository) topTenCompletedGames(mode string, since time.Time, rank func(game quiz.Game) int64) ([]quiz.Game, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var all []quiz.Game
+	for _, game := range r.games {
+		if !game.InProgress && game.Mode == mode && game.Completed.After(since) {
+			all = append(all, game)
+		}
+	}
+
+	sort.SliceStable(all, func(i, j int) bool { return rank(all[i]) > rank(all[j]) })
+
+	if len(all) > 10 {
+		all = all[:10]
+	}
+	return all, nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// migration is one numbered, forward-only change to the schema. Each runs in
+// its own transaction together with the schema_migrations row recording it.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+func execMigration(query string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+// migrationDialect holds the statements that differ between backends for
+// managing the schema_migrations table. lock, if set, is run first in each
+// migration's transaction and holds off other servers migrating the same
+// database until it ends.
+type migrationDialect struct {
+	createTable string
+	insert      string
+	lock        string
+}
+
+// migrationLockKey is the Postgres advisory lock servers take while
+// migrating, an arbitrary number no other lock in the database uses.
+const migrationLockKey = 885_0001
+
+var sqliteMigrationDialect = migrationDialect{
+	createTable: `--sql
+	CREATE TABLE IF NOT EXISTS schema_migrations(
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied BLOB NOT NULL
+	);`,
+	insert: "INSERT INTO schema_migrations(version, name, applied) values(?,?,?)",
+}
+
+var postgresMigrationDialect = migrationDialect{
+	createTable: `--sql
+	CREATE TABLE IF NOT EXISTS schema_migrations(
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied TIMESTAMPTZ NOT NULL
+	);`,
+	insert: "INSERT INTO schema_migrations(version, name, applied) values($1,$2,$3)",
+	// Replicas starting together against one database would otherwise both
+	// apply the same migration.
+	lock: "SELECT pg_advisory_xact_lock($1)",
+}
+
+// runMigrations applies, in order, every migration newer than the highest
+// version recorded in schema_migrations and returns the ones it applied.
+// Migrations another server applies meanwhile are skipped.
+func runMigrations(db *sql.DB, dialect migrationDialect, migrations []migration) ([]migration, error) {
+	if _, err := db.Exec(dialect.createTable); err != nil {
+		return nil, err
+	}
+
+	current, err := currentVersion(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []migration
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		ok, err := applyMigration(db, dialect, m)
+		if err != nil {
+			return applied, err
+		}
+		if !ok {
+			continue
+		}
+
+		log.Printf("Applied migration %d %s", m.version, m.name)
+		applied = append(applied, m)
+	}
+
+	return applied, nil
+}
+
+func currentVersion(db execer) (int, error) {
+	var current int
+	err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	return current, err
+}
+
+// applyMigration applies m unless, once it holds the dialect's lock, it finds
+// that another server already has. It reports whether it applied m.
+func applyMigration(db *sql.DB, dialect migrationDialect, m migration) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if dialect.lock != "" {
+		if _, err := tx.Exec(dialect.lock, migrationLockKey); err != nil {
+			return false, err
+		}
+	}
+
+	current, err := currentVersion(tx)
+	if err != nil {
+		return false, err
+	}
+	if m.version <= current {
+		return false, nil
+	}
+
+	if err := m.up(tx); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(dialect.insert, m.version, m.name, time.Now().UTC()); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
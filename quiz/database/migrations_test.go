@@ -0,0 +1,193 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"me885/fintech-or-furniture/quiz"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// baselineSchema is the schema databases were created with before migrations
+// were tracked.
+const baselineSchema = `--sql
+    CREATE TABLE IF NOT EXISTS questions(
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        question TEXT NOT NULL UNIQUE,
+        answer INTEGER NOT NULL
+    );
+
+	CREATE TABLE IF NOT EXISTS games(
+        id BLOB PRIMARY KEY,
+        playerName TEXT NOT NULL,
+        questionsAnswered INTEGER NOT NULL,
+        score INTEGER NOT NULL,
+        inProgress INTEGER NOT NULL,
+		created BLOB,
+		completed BLOB
+    );
+
+	CREATE TABLE IF NOT EXISTS gameQuestions(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		gameId BLOB NOT NULL,
+		QuestionId INTEGER NOT NULL
+	);
+`
+
+func openTestSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func appliedVersions(t *testing.T, db *sql.DB) []int {
+	rows, err := db.Query("SELECT version FROM schema_migrations ORDER BY version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var versions []int
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+func TestMigrate_FromBaselineSchema(t *testing.T) {
+	db := openTestSQLite(t)
+
+	if _, err := db.Exec(baselineSchema); err != nil {
+		t.Fatal(err)
+	}
+
+	gameId := uuid.New()
+	if _, err := db.Exec("INSERT INTO games(id, playerName, questionsAnswered, score, inProgress) values(?,?,?,?,?)", gameId, "bob", 3, 2, true); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSQLiteRepository(db)
+	if err := repo.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	game, err := repo.GetGameById(gameId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if game.PlayerName != "bob" || game.Score != 2 || game.CurrentQuestionId != 0 {
+		t.Fatal(game)
+	}
+
+	versions := appliedVersions(t, db)
+	if len(versions) != len(sqliteMigrations) || versions[len(versions)-1] != sqliteMigrations[len(sqliteMigrations)-1].version {
+		t.Fatal(versions)
+	}
+}
+
+func TestMigrate_IsIdempotent(t *testing.T) {
+	db := openTestSQLite(t)
+
+	repo := NewSQLiteRepository(db)
+	if err := repo.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := runMigrations(db, sqliteMigrationDialect, sqliteMigrations)
+	if err != nil || len(applied) != 0 {
+		t.Fatal(applied, err)
+	}
+}
+
+func TestMigrate_SkipsMigrationsAppliedMeanwhile(t *testing.T) {
+	db := openTestSQLite(t)
+
+	migrations := []migration{
+		{1, "create a", execMigration("CREATE TABLE a(id INTEGER)")},
+	}
+
+	// Another server applies the migration after this one read the version.
+	if _, err := runMigrations(db, sqliteMigrationDialect, migrations); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := applyMigration(db, sqliteMigrationDialect, migrations[0])
+	if err != nil || applied {
+		t.Fatal(applied, err)
+	}
+}
+
+func TestMigrate_FailedMigrationRollsBack(t *testing.T) {
+	db := openTestSQLite(t)
+
+	migrations := []migration{
+		{1, "create a", execMigration("CREATE TABLE a(id INTEGER)")},
+		{2, "create b then fail", func(tx *sql.Tx) error {
+			if _, err := tx.Exec("CREATE TABLE b(id INTEGER)"); err != nil {
+				return err
+			}
+			return errors.New("boom")
+		}},
+	}
+
+	applied, err := runMigrations(db, sqliteMigrationDialect, migrations)
+	if err == nil || len(applied) != 1 {
+		t.Fatal(applied, err)
+	}
+
+	versions := appliedVersions(t, db)
+	if len(versions) != 1 || versions[0] != 1 {
+		t.Fatal(versions)
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'b'").Scan(&count)
+	if count != 0 {
+		t.Fatal("table b should have been rolled back")
+	}
+}
+
+func TestMigrate_LabelsToAnswerOptions(t *testing.T) {
+	db := openTestSQLite(t)
+
+	if _, err := runMigrations(db, sqliteMigrationDialect, sqliteMigrations[:9]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Before answer options, answers were an index into the pack's labels.
+	if _, err := db.Exec(`--sql
+		INSERT INTO packs(slug, name, firstLabel, secondLabel) values('pokemon-or-pharma', 'Pokémon or Pharma', 'Pokémon', 'Pharma');
+		INSERT INTO questions(question, answer, packId) values('PAX', 1, 1), ('ZYNGA', 0, 1), ('XATU', 0, 2), ('OTEZLA', 1, 2);
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSQLiteRepository(db)
+	if err := repo.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	pharma, err := repo.GetPackById(2)
+	if err != nil || len(pharma.Options) != 2 || pharma.Options[0].Label != "Pokémon" || pharma.Options[1].Label != "Pharma" {
+		t.Fatal(pharma, err)
+	}
+
+	expected := map[string]quiz.Answer{"PAX": quiz.Furniture, "ZYNGA": quiz.Fintech, "XATU": pharma.Options[0].Id, "OTEZLA": pharma.Options[1].Id}
+	for word, answer := range expected {
+		question, err := repo.GetQuestionByText(word)
+		if err != nil || question.Answer != answer {
+			t.Fatal(word, question, err)
+		}
+	}
+}
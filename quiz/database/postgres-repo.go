@@ -0,0 +1,986 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"me885/fintech-or-furniture/quiz"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+var _ quiz.Repository = (*PostgresRepository)(nil)
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresRepository) Migrate() error {
+	_, err := runMigrations(r.db, postgresMigrationDialect, postgresMigrations)
+	return err
+}
+
+func (r *PostgresRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *PostgresRepository) Close() error {
+	return r.db.Close()
+}
+
+var postgresMigrations = []migration{
+	{1, "create questions, games and gameQuestions", execMigration(`--sql
+
+    CREATE TABLE IF NOT EXISTS questions(
+        id BIGSERIAL PRIMARY KEY,
+        question TEXT NOT NULL UNIQUE,
+        answer INTEGER NOT NULL
+    );
+
+	CREATE TABLE IF NOT EXISTS games(
+        id UUID PRIMARY KEY,
+        playerName TEXT NOT NULL,
+        questionsAnswered INTEGER NOT NULL,
+        score INTEGER NOT NULL,
+        inProgress BOOLEAN NOT NULL,
+		created TIMESTAMPTZ NOT NULL,
+		completed TIMESTAMPTZ
+    );
+
+	CREATE TABLE IF NOT EXISTS gameQuestions(
+		id BIGSERIAL PRIMARY KEY,
+		gameId UUID NOT NULL,
+		questionId BIGINT NOT NULL
+	);
+    `)},
+	{2, "add games.currentQuestionId", execMigration(`--sql
+	ALTER TABLE games ADD COLUMN IF NOT EXISTS currentQuestionId BIGINT NOT NULL DEFAULT 0;
+	`)},
+	{3, "add questions.source and questions.notes", execMigration(`--sql
+	ALTER TABLE questions ADD COLUMN source TEXT NOT NULL DEFAULT '';
+	ALTER TABLE questions ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+	`)},
+	{4, "add questions.disabled and questionAudit", execMigration(`--sql
+	ALTER TABLE questions ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+	CREATE TABLE questionAudit(
+		id BIGSERIAL PRIMARY KEY,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		questionId BIGINT NOT NULL,
+		question TEXT NOT NULL,
+		detail TEXT NOT NULL,
+		at TIMESTAMPTZ NOT NULL
+	);
+	`)},
+	{5, "add games.mode and games.mistakes", execMigration(`--sql
+	ALTER TABLE games ADD COLUMN mode TEXT NOT NULL DEFAULT 'classic';
+	ALTER TABLE games ADD COLUMN mistakes INTEGER NOT NULL DEFAULT 0;
+	`)},
+	{6, "add games.questionServed", execMigration(`--sql
+	ALTER TABLE games ADD COLUMN questionServed TIMESTAMPTZ;
+	`)},
+	{7, "add games.streak and games.bestStreak", execMigration(`--sql
+	ALTER TABLE games ADD COLUMN streak INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE games ADD COLUMN bestStreak INTEGER NOT NULL DEFAULT 0;
+	`)},
+	{8, "add games.outOfQuestions", execMigration(`--sql
+	ALTER TABLE games ADD COLUMN outOfQuestions BOOLEAN NOT NULL DEFAULT FALSE;
+	`)},
+	{9, "add packs, questions.packId and games.packId", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`--sql
+		CREATE TABLE packs(
+			id BIGSERIAL PRIMARY KEY,
+			slug TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL,
+			firstLabel TEXT NOT NULL,
+			secondLabel TEXT NOT NULL
+		);
+
+		ALTER TABLE questions ADD COLUMN packId BIGINT NOT NULL DEFAULT 1;
+		ALTER TABLE games ADD COLUMN packId BIGINT NOT NULL DEFAULT 1;
+		`)
+		if err != nil {
+			return err
+		}
+
+		if err := insertDefaultPack(tx, "INSERT INTO packs(id, slug, name, firstLabel, secondLabel) values($1,$2,$3,$4,$5)"); err != nil {
+			return err
+		}
+
+		// The default pack was inserted with an explicit id, so move the
+		// sequence past it.
+		_, err = tx.Exec("SELECT setval(pg_get_serial_sequence('packs', 'id'), (SELECT MAX(id) FROM packs))")
+		return err
+	}},
+	{10, "move pack labels to answerOptions", execMigration(`--sql
+	CREATE TABLE answerOptions(
+		id BIGSERIAL PRIMARY KEY,
+		packId BIGINT NOT NULL,
+		position INTEGER NOT NULL,
+		label TEXT NOT NULL,
+		UNIQUE(packId, position),
+		UNIQUE(packId, label)
+	);
+
+	ALTER TABLE questions ALTER COLUMN answer TYPE BIGINT;
+	` + moveLabelsToAnswerOptions)},
+	{11, "add questions.timesServed and questions.timesCorrect", execMigration(`--sql
+	ALTER TABLE questions ADD COLUMN timesServed BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE questions ADD COLUMN timesCorrect BIGINT NOT NULL DEFAULT 0;
+	`)},
+	{12, "add games.seed", execMigration(`--sql
+	ALTER TABLE games ADD COLUMN seed BIGINT NOT NULL DEFAULT 0;
+	`)},
+	{13, "add games.challengeDay", execMigration(`--sql
+	ALTER TABLE games ADD COLUMN challengeDay TEXT NOT NULL DEFAULT '';
+
+	CREATE UNIQUE INDEX games_challengeDay_playerName ON games(challengeDay, lower(playerName)) WHERE challengeDay != '';
+	`)},
+	{14, "add rooms and games.roomCode", execMigration(`--sql
+	CREATE TABLE rooms(
+		code TEXT PRIMARY KEY,
+		packId BIGINT NOT NULL,
+		hostGameId UUID NOT NULL,
+		state TEXT NOT NULL,
+		currentQuestionId BIGINT NOT NULL DEFAULT 0,
+		questionsAsked BIGINT NOT NULL DEFAULT 0,
+		seed BIGINT NOT NULL,
+		created TIMESTAMPTZ NOT NULL
+	);
+
+	ALTER TABLE games ADD COLUMN roomCode TEXT NOT NULL DEFAULT '';
+
+	CREATE INDEX games_roomCode ON games(roomCode) WHERE roomCode != '';
+	`)},
+	{15, "add players, sessions and games.playerId", execMigration(`--sql
+	CREATE TABLE players(
+		id BIGSERIAL PRIMARY KEY,
+		username TEXT NOT NULL,
+		passwordHash TEXT NOT NULL,
+		created TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE UNIQUE INDEX players_username ON players(lower(username));
+
+	CREATE TABLE sessions(
+		token TEXT PRIMARY KEY,
+		playerId BIGINT NOT NULL,
+		created TIMESTAMPTZ NOT NULL,
+		expires TIMESTAMPTZ NOT NULL
+	);
+
+	ALTER TABLE games ADD COLUMN playerId BIGINT NOT NULL DEFAULT 0;
+
+	CREATE INDEX games_playerId ON games(playerId) WHERE playerId != 0;
+	`)},
+	{16, "add gameSessions and games.sessionId", execMigration(`--sql
+	CREATE TABLE gameSessions(
+		id BIGSERIAL PRIMARY KEY,
+		token TEXT NOT NULL,
+		created TIMESTAMPTZ NOT NULL,
+		expires TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE UNIQUE INDEX gameSessions_token ON gameSessions(token);
+
+	ALTER TABLE games ADD COLUMN sessionId BIGINT NOT NULL DEFAULT 0;
+
+	CREATE INDEX games_sessionId ON games(sessionId) WHERE sessionId != 0;
+	`)},
+	{17, "add gameQuestionStats", execMigration(`--sql
+	CREATE TABLE gameQuestionStats(
+		gameId UUID NOT NULL,
+		questionId BIGINT NOT NULL,
+		timesServed BIGINT NOT NULL,
+		timesCorrect BIGINT NOT NULL,
+		PRIMARY KEY(gameId, questionId)
+	);
+	`)},
+	{18, "add rooms.questionServed", execMigration(`--sql
+	ALTER TABLE rooms ADD COLUMN questionServed TIMESTAMPTZ;
+	`)},
+	{19, "add games_roomCode_playerName index", execMigration(`--sql
+	CREATE UNIQUE INDEX games_roomCode_playerName ON games(roomCode, lower(playerName)) WHERE roomCode != '';
+	`)},
+}
+
+// CreatePack adds the pack and its options together, filling in their ids.
+func (r *PostgresRepository) CreatePack(pack quiz.Pack) (*quiz.Pack, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow("INSERT INTO packs(slug, name) values($1,$2) RETURNING id", pack.Slug, pack.Name)
+	if err := row.Scan(&pack.Id); err != nil {
+		if isPostgresUniqueErr(err) {
+			return nil, ErrDuplicate
+		}
+		return nil, err
+	}
+
+	pack.Options = append([]quiz.AnswerOption(nil), pack.Options...)
+	for i := range pack.Options {
+		row := tx.QueryRow("INSERT INTO answerOptions(packId, position, label) values($1,$2,$3) RETURNING id", pack.Id, i, pack.Options[i].Label)
+		if err := row.Scan(&pack.Options[i].Id); err != nil {
+			if isPostgresUniqueErr(err) {
+				return nil, ErrDuplicate
+			}
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &pack, nil
+}
+
+func (r *PostgresRepository) GetPackById(id int64) (*quiz.Pack, error) {
+	return r.getPack(r.db.QueryRow("SELECT "+packColumns+" FROM packs WHERE id = $1", id))
+}
+
+func (r *PostgresRepository) GetPackBySlug(slug string) (*quiz.Pack, error) {
+	return r.getPack(r.db.QueryRow("SELECT "+packColumns+" FROM packs WHERE slug = $1", slug))
+}
+
+func (r *PostgresRepository) getPack(row *sql.Row) (*quiz.Pack, error) {
+	pack, err := scanPack(row)
+	if err != nil {
+		return nil, err
+	}
+
+	packs := []quiz.Pack{*pack}
+	if err := loadOptions(r.db, packs); err != nil {
+		return nil, err
+	}
+	return &packs[0], nil
+}
+
+func (r *PostgresRepository) AllPacks() ([]quiz.Pack, error) {
+	rows, err := r.db.Query("SELECT " + packColumns + " FROM packs ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	packs, err := scanPacks(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return packs, loadOptions(r.db, packs)
+}
+
+func (r *PostgresRepository) UpdatePack(pack quiz.Pack) (*quiz.Pack, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE packs SET name = $1 WHERE id = $2", pack.Name, pack.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrNotExists
+	}
+
+	var position int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(position), -1) + 1 FROM answerOptions WHERE packId = $1", pack.Id).Scan(&position); err != nil {
+		return nil, err
+	}
+
+	for _, option := range pack.Options {
+		if option.Id != 0 {
+			continue
+		}
+
+		if _, err := tx.Exec("INSERT INTO answerOptions(packId, position, label) values($1,$2,$3)", pack.Id, position, option.Label); err != nil {
+			if isPostgresUniqueErr(err) {
+				return nil, ErrDuplicate
+			}
+			return nil, err
+		}
+		position++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.GetPackById(pack.Id)
+}
+
+func (r *PostgresRepository) AddGameQuestion(gameId uuid.UUID, questionId int64) error {
+	_, err := r.db.Exec("INSERT INTO gameQuestions(gameId, questionId) values($1,$2)", gameId, questionId)
+
+	return err
+}
+
+func (r *PostgresRepository) ClaimCurrentQuestion(gameId uuid.UUID, questionId int64) error {
+	res, err := r.db.Exec(
+		"UPDATE games SET currentQuestionId = 0 WHERE id = $1 AND currentQuestionId = $2 AND currentQuestionId != 0",
+		gameId,
+		questionId)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUpdateFailed
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) RemoveGameQuestions(gameId uuid.UUID) error {
+	_, err := r.db.Exec("DELETE FROM gameQuestions WHERE gameId = $1", gameId)
+
+	return err
+}
+
+func (r *PostgresRepository) GetUnansweredQuestions(gameId uuid.UUID) ([]quiz.Question, error) {
+	rows, err := r.db.Query(`--sql
+		SELECT `+snapshotQuestionColumns+`
+		FROM questions q
+		LEFT JOIN gameQuestionStats s ON s.gameId = $1 AND s.questionId = q.id
+		WHERE q.id NOT IN (
+			SELECT questionId
+			FROM gameQuestions
+			WHERE gameId = $1
+		) AND NOT q.disabled
+		AND q.packId = (SELECT packId FROM games WHERE id = $1)
+		ORDER BY q.id`,
+		gameId)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanQuestions(rows)
+}
+
+func (r *PostgresRepository) CreateQuestion(question quiz.Question) (*quiz.Question, error) {
+	return createPostgresQuestion(r.db, question)
+}
+
+func createPostgresQuestion(db execer, question quiz.Question) (*quiz.Question, error) {
+	if question.PackId == 0 {
+		question.PackId = quiz.DefaultPackId
+	}
+
+	row := db.QueryRow(
+		"INSERT INTO questions(question, answer, source, notes, disabled, packId) values($1,$2,$3,$4,$5,$6) RETURNING id",
+		question.Question,
+		question.Answer,
+		question.Source,
+		question.Notes,
+		question.Disabled,
+		question.PackId)
+
+	if err := row.Scan(&question.Id); err != nil {
+		if isPostgresUniqueErr(err) {
+			return nil, ErrDuplicate
+		}
+		return nil, err
+	}
+
+	return &question, nil
+}
+
+func (r *PostgresRepository) GetQuestionById(id int64) (*quiz.Question, error) {
+	row := r.db.QueryRow("SELECT "+questionColumns+" FROM questions WHERE id = $1", id)
+
+	return scanQuestion(row)
+}
+
+func (r *PostgresRepository) GetQuestionByText(text string) (*quiz.Question, error) {
+	row := r.db.QueryRow("SELECT "+questionColumns+" FROM questions WHERE question = $1", text)
+
+	return scanQuestion(row)
+}
+
+func (r *PostgresRepository) UpdateQuestion(question quiz.Question) (*quiz.Question, error) {
+	return updatePostgresQuestion(r.db, question)
+}
+
+func updatePostgresQuestion(db execer, question quiz.Question) (*quiz.Question, error) {
+	res, err := db.Exec(
+		"UPDATE questions SET question = $1, answer = $2, source = $3, notes = $4, disabled = $5, packId = $6 WHERE id = $7",
+		question.Question,
+		question.Answer,
+		question.Source,
+		question.Notes,
+		question.Disabled,
+		question.PackId,
+		question.Id)
+
+	if err != nil {
+		if isPostgresUniqueErr(err) {
+			return nil, ErrDuplicate
+		}
+		return nil, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrUpdateFailed
+	}
+
+	return &question, nil
+}
+
+func (r *PostgresRepository) AllQuestions() ([]quiz.Question, error) {
+	rows, err := r.db.Query("SELECT " + questionColumns + " FROM questions ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	return scanQuestions(rows)
+}
+
+func (r *PostgresRepository) ListQuestions(search string, offset int, limit int) ([]quiz.Question, int64, error) {
+	pattern := "%" + search + "%"
+
+	var total int64
+	row := r.db.QueryRow("SELECT COUNT(*) FROM questions WHERE question ILIKE $1 OR source ILIKE $1 OR notes ILIKE $1", pattern)
+	if err := row.Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(`--sql
+		SELECT `+questionColumns+`
+		FROM questions
+		WHERE question ILIKE $1 OR source ILIKE $1 OR notes ILIKE $1
+		ORDER BY id
+		LIMIT $2 OFFSET $3`,
+		pattern, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	questions, err := scanQuestions(rows)
+	return questions, total, err
+}
+
+func (r *PostgresRepository) DeleteQuestion(id int64) error {
+	return deletePostgresQuestion(r.db, id)
+}
+
+func deletePostgresQuestion(db execer, id int64) error {
+	res, err := db.Exec("DELETE FROM questions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrDeleteFailed
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) RecordAnswer(questionId int64, correct bool) error {
+	res, err := r.db.Exec(
+		"UPDATE questions SET timesServed = timesServed + 1, timesCorrect = timesCorrect + $1 WHERE id = $2",
+		boolToInt(correct), questionId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUpdateFailed
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) AddAuditEntry(entry quiz.AuditEntry) error {
+	return addPostgresAuditEntry(r.db, entry)
+}
+
+func addPostgresAuditEntry(db execer, entry quiz.AuditEntry) error {
+	_, err := db.Exec(
+		"INSERT INTO questionAudit(actor, action, questionId, question, detail, at) values($1,$2,$3,$4,$5,$6)",
+		entry.Actor,
+		entry.Action,
+		entry.QuestionId,
+		entry.Question,
+		entry.Detail,
+		entry.At.UTC())
+
+	return err
+}
+
+func (r *PostgresRepository) CreateQuestionAudited(question quiz.Question, entry quiz.AuditEntry) (*quiz.Question, error) {
+	var created *quiz.Question
+	err := inTransaction(r.db, func(tx *sql.Tx) error {
+		var err error
+		if created, err = createPostgresQuestion(tx, question); err != nil {
+			return err
+		}
+		return addPostgresAuditEntry(tx, entry.For(*created))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *PostgresRepository) UpdateQuestionAudited(question quiz.Question, entry quiz.AuditEntry) (*quiz.Question, error) {
+	var updated *quiz.Question
+	err := inTransaction(r.db, func(tx *sql.Tx) error {
+		var err error
+		if updated, err = updatePostgresQuestion(tx, question); err != nil {
+			return err
+		}
+		return addPostgresAuditEntry(tx, entry.For(*updated))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (r *PostgresRepository) DeleteQuestionAudited(question quiz.Question, entry quiz.AuditEntry) error {
+	return inTransaction(r.db, func(tx *sql.Tx) error {
+		if err := deletePostgresQuestion(tx, question.Id); err != nil {
+			return err
+		}
+		return addPostgresAuditEntry(tx, entry.For(question))
+	})
+}
+
+func (r *PostgresRepository) AuditEntries(limit int) ([]quiz.AuditEntry, error) {
+	rows, err := r.db.Query("SELECT id, actor, action, questionId, question, detail, at FROM questionAudit ORDER BY id DESC LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAuditEntries(rows)
+}
+
+func (r *PostgresRepository) CountQuestions() (int64, error) {
+	row := r.db.QueryRow("SELECT COUNT(*) FROM questions")
+
+	var count int64
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *PostgresRepository) CountEnabledQuestions(packId int64) (int64, error) {
+	row := r.db.QueryRow("SELECT COUNT(*) FROM questions WHERE NOT disabled AND packId = $1", packId)
+
+	var count int64
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *PostgresRepository) CreatePlayer(username string, passwordHash string) (*quiz.Player, error) {
+	player := quiz.Player{Username: username, PasswordHash: passwordHash, Created: postgresNow()}
+
+	err := r.db.QueryRow(
+		"INSERT INTO players(username, passwordHash, created) values($1,$2,$3) RETURNING id",
+		player.Username, player.PasswordHash, player.Created).Scan(&player.Id)
+	if err != nil {
+		if isPostgresUniqueErr(err) {
+			return nil, ErrDuplicate
+		}
+		return nil, err
+	}
+
+	return &player, nil
+}
+
+func (r *PostgresRepository) GetPlayerById(id int64) (*quiz.Player, error) {
+	row := r.db.QueryRow("SELECT "+playerColumns+" FROM players WHERE id = $1", id)
+
+	return scanPlayer(row)
+}
+
+func (r *PostgresRepository) GetPlayerByUsername(username string) (*quiz.Player, error) {
+	row := r.db.QueryRow("SELECT "+playerColumns+" FROM players WHERE lower(username) = lower($1)", username)
+
+	return scanPlayer(row)
+}
+
+func (r *PostgresRepository) PlayerGames(playerId int64) ([]quiz.Game, error) {
+	rows, err := r.db.Query("SELECT "+gameColumns+" FROM games WHERE playerId = $1 ORDER BY created DESC", playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanGames(rows)
+}
+
+func (r *PostgresRepository) CreateSession(playerId int64, expires time.Time) (*quiz.Session, error) {
+	session := quiz.Session{Token: quiz.NewSessionToken(), PlayerId: playerId, Created: postgresNow(), Expires: expires.UTC().Truncate(time.Microsecond)}
+
+	_, err := r.db.Exec("INSERT INTO sessions(token, playerId, created, expires) values($1,$2,$3,$4)", session.Token, session.PlayerId, session.Created, session.Expires)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (r *PostgresRepository) GetSession(token string) (*quiz.Session, error) {
+	row := r.db.QueryRow("SELECT "+sessionColumns+" FROM sessions WHERE token = $1", token)
+
+	return scanSession(row)
+}
+
+func (r *PostgresRepository) DeleteSession(token string) error {
+	res, err := r.db.Exec("DELETE FROM sessions WHERE token = $1", token)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrDeleteFailed
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) CreateGameSession(expires time.Time) (*quiz.GameSession, error) {
+	session := quiz.GameSession{Token: quiz.NewSessionToken(), Created: postgresNow(), Expires: expires.UTC().Truncate(time.Microsecond)}
+
+	err := r.db.QueryRow(
+		"INSERT INTO gameSessions(token, created, expires) values($1,$2,$3) RETURNING id",
+		session.Token, session.Created, session.Expires).Scan(&session.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (r *PostgresRepository) GetGameSession(token string) (*quiz.GameSession, error) {
+	row := r.db.QueryRow("SELECT "+gameSessionColumns+" FROM gameSessions WHERE token = $1", token)
+
+	return scanGameSession(row)
+}
+
+func (r *PostgresRepository) RotateGameSession(id int64, expires time.Time) (*quiz.GameSession, error) {
+	row := r.db.QueryRow(
+		"UPDATE gameSessions SET token = $1, expires = $2 WHERE id = $3 RETURNING "+gameSessionColumns,
+		quiz.NewSessionToken(), expires.UTC().Truncate(time.Microsecond), id)
+
+	return scanGameSession(row)
+}
+
+func (r *PostgresRepository) DeleteGameSession(token string) error {
+	res, err := r.db.Exec("DELETE FROM gameSessions WHERE token = $1", token)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrDeleteFailed
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) GetSessionGame(sessionId int64, gameId uuid.UUID) (*quiz.Game, error) {
+	if gameId == uuid.Nil {
+		row := r.db.QueryRow("SELECT "+gameColumns+" FROM games WHERE sessionId = $1 ORDER BY created DESC LIMIT 1", sessionId)
+		return scanGame(row)
+	}
+
+	row := r.db.QueryRow("SELECT "+gameColumns+" FROM games WHERE sessionId = $1 AND id = $2", sessionId, gameId)
+	return scanGame(row)
+}
+
+func (r *PostgresRepository) CreateGame(start quiz.NewGame) (*quiz.Game, error) {
+	return r.insertGame(newGame(start, postgresNow()))
+}
+
+// postgresNow is the current time as precisely as timestamptz keeps it, so
+// games read back compare equal to the ones created.
+func postgresNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// insertGame adds the game along with a snapshot of its pack's question
+// statistics, which its questions are picked with.
+func (r *PostgresRepository) insertGame(game quiz.Game) (*quiz.Game, error) {
+	err := inTransaction(r.db, func(tx *sql.Tx) error {
+		// Holding the room's row until the game is in keeps the room from
+		// starting without it.
+		if game.RoomCode != "" {
+			if err := checkRoomLobby(tx, "SELECT state FROM rooms WHERE code = $1 FOR SHARE", game.RoomCode); err != nil {
+				return err
+			}
+		}
+
+		if err := insertPostgresGame(tx, game); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`--sql
+			INSERT INTO gameQuestionStats(gameId, questionId, timesServed, timesCorrect)
+			SELECT $1, id, timesServed, timesCorrect
+			FROM questions
+			WHERE packId = $2 AND timesServed > 0`,
+			game.Id,
+			game.PackId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &game, nil
+}
+
+func insertPostgresGame(tx *sql.Tx, game quiz.Game) error {
+	_, err := tx.Exec(
+		"INSERT INTO games(id, playerName, questionsAnswered, score, inProgress, created, mode, packId, seed, challengeDay, roomCode, playerId, sessionId) values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)",
+		game.Id,
+		game.PlayerName,
+		game.QuestionsAnswered,
+		game.Score,
+		game.InProgress,
+		game.Created,
+		game.Mode,
+		game.PackId,
+		game.Seed,
+		game.ChallengeDay,
+		game.RoomCode,
+		game.PlayerId,
+		game.SessionId)
+
+	if isPostgresUniqueErr(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *PostgresRepository) GetGameById(id uuid.UUID) (*quiz.Game, error) {
+	row := r.db.QueryRow("SELECT "+gameColumns+" FROM games WHERE id = $1", id)
+
+	return scanGame(row)
+}
+
+func (r *PostgresRepository) UpdateGame(game *quiz.Game) (*quiz.Game, error) {
+	res, err := r.db.Exec(
+		"UPDATE games SET playerName = $1, questionsAnswered = $2, score = $3, inProgress = $4, created = $5, completed = $6, currentQuestionId = $7, mode = $8, mistakes = $9, questionServed = $10, streak = $11, bestStreak = $12, outOfQuestions = $13, packId = $14, seed = $15, challengeDay = $16, roomCode = $17, playerId = $18, sessionId = $19 WHERE id = $20",
+		game.PlayerName,
+		game.QuestionsAnswered,
+		game.Score,
+		game.InProgress,
+		game.Created,
+		nullTime(game.Completed),
+		game.CurrentQuestionId,
+		game.Mode,
+		game.Mistakes,
+		nullTime(game.QuestionServed),
+		game.Streak,
+		game.BestStreak,
+		game.OutOfQuestions,
+		game.PackId,
+		game.Seed,
+		game.ChallengeDay,
+		game.RoomCode,
+		game.PlayerId,
+		game.SessionId,
+		game.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrUpdateFailed
+	}
+
+	return game, nil
+}
+
+func (r *PostgresRepository) AllGames() ([]quiz.Game, error) {
+	rows, err := r.db.Query("SELECT " + gameColumns + " FROM games ORDER BY created")
+	if err != nil {
+		return nil, err
+	}
+
+	return scanGames(rows)
+}
+
+func (r *PostgresRepository) CreateRoom(code string, packId int64, hostGameId uuid.UUID) (*quiz.Room, error) {
+	room := quiz.Room{Code: code, PackId: packId, HostGameId: hostGameId, State: quiz.RoomLobby, Seed: rand.Int63(), Created: postgresNow()}
+
+	_, err := r.db.Exec(
+		"INSERT INTO rooms(code, packId, hostGameId, state, seed, created) values($1,$2,$3,$4,$5,$6)",
+		room.Code,
+		room.PackId,
+		room.HostGameId,
+		room.State,
+		room.Seed,
+		room.Created)
+
+	if err != nil {
+		if isPostgresUniqueErr(err) {
+			return nil, ErrDuplicate
+		}
+		return nil, err
+	}
+
+	return &room, nil
+}
+
+func (r *PostgresRepository) GetRoomByCode(code string) (*quiz.Room, error) {
+	row := r.db.QueryRow("SELECT "+roomColumns+" FROM rooms WHERE code = $1", code)
+
+	return scanRoom(row)
+}
+
+func (r *PostgresRepository) UpdateRoom(room *quiz.Room, previous quiz.Room) (*quiz.Room, error) {
+	res, err := r.db.Exec(
+		"UPDATE rooms SET state = $1, currentQuestionId = $2, questionsAsked = $3, questionServed = $4 WHERE code = $5 AND state = $6 AND questionsAsked = $7",
+		room.State,
+		room.CurrentQuestionId,
+		room.QuestionsAsked,
+		nullTime(room.QuestionServed),
+		room.Code,
+		previous.State,
+		previous.QuestionsAsked)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrUpdateFailed
+	}
+
+	return room, nil
+}
+
+func (r *PostgresRepository) RoomGames(code string) ([]quiz.Game, error) {
+	rows, err := r.db.Query("SELECT "+gameColumns+" FROM games WHERE roomCode = $1 ORDER BY created", code)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanGames(rows)
+}
+
+func (r *PostgresRepository) TopTenCompletedGames(mode string, since time.Time) ([]quiz.Game, error) {
+	rows, err := r.db.Query(`--sql
+	SELECT `+gameColumns+`
+	FROM games
+	WHERE NOT inProgress AND mode = $1 AND completed > $2
+	ORDER BY score
+	DESC LIMIT 10
+	`, mode, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanGames(rows)
+}
+
+func (r *PostgresRepository) TopTenStreaks(mode string, since time.Time) ([]quiz.Game, error) {
+	rows, err := r.db.Query(`--sql
+	SELECT `+gameColumns+`
+	FROM games
+	WHERE NOT inProgress AND mode = $1 AND completed > $2
+	ORDER BY bestStreak
+	DESC LIMIT 10
+	`, mode, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanGames(rows)
+}
+
+func (r *PostgresRepository) TopTenDailyGames(day string) ([]quiz.Game, error) {
+	rows, err := r.db.Query(`--sql
+	SELECT `+gameColumns+`
+	FROM games
+	WHERE NOT inProgress AND challengeDay = $1
+	ORDER BY score DESC, completed
+	LIMIT 10
+	`, day)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanGames(rows)
+}
+
+func isPostgresUniqueErr(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// nullTime stores the zero time as NULL rather than year one.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,1005 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"me885/fintech-or-furniture/quiz"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// testRepository is the contract every quiz.Repository implementation must
+// pass. newRepository should return an empty, migrated repository.
+func testRepository(t *testing.T, newRepository func(t *testing.T) quiz.Repository) {
+	t.Run("CreateQuestion", func(t *testing.T) {
+		repo := newRepository(t)
+
+		created, err := repo.CreateQuestion(quiz.Question{Question: "PAX", Answer: quiz.Furniture})
+		if err != nil || created.Id == 0 {
+			t.Fatal(created, err)
+		}
+
+		question, err := repo.GetQuestionById(created.Id)
+		if err != nil || question.Question != "PAX" || question.Answer != quiz.Furniture {
+			t.Fatal(question, err)
+		}
+
+		count, err := repo.CountQuestions()
+		if err != nil || count != 1 {
+			t.Fatal(count, err)
+		}
+	})
+
+	t.Run("CountEnabledQuestions", func(t *testing.T) {
+		repo := newRepository(t)
+
+		repo.CreateQuestion(quiz.Question{Question: "PAX", Answer: quiz.Furniture})
+		repo.CreateQuestion(quiz.Question{Question: "ZYNGA", Answer: quiz.Fintech, Disabled: true})
+
+		count, err := repo.CountEnabledQuestions(quiz.DefaultPackId)
+		if err != nil || count != 1 {
+			t.Fatal(count, err)
+		}
+	})
+
+	t.Run("CreateQuestion_Duplicate", func(t *testing.T) {
+		repo := newRepository(t)
+
+		repo.CreateQuestion(quiz.Question{Question: "PAX", Answer: quiz.Furniture})
+
+		_, err := repo.CreateQuestion(quiz.Question{Question: "PAX", Answer: quiz.Fintech})
+		if !errors.Is(err, quiz.ErrDuplicate) {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("UpdateQuestion", func(t *testing.T) {
+		repo := newRepository(t)
+
+		created, _ := repo.CreateQuestion(quiz.Question{Question: "PAX", Answer: quiz.Furniture})
+		repo.CreateQuestion(quiz.Question{Question: "LACK", Answer: quiz.Furniture})
+
+		created.Source = "Ikea"
+		created.Notes = "wardrobe"
+		if _, err := repo.UpdateQuestion(*created); err != nil {
+			t.Fatal(err)
+		}
+
+		question, err := repo.GetQuestionByText("PAX")
+		if err != nil || question.Id != created.Id || question.Source != "Ikea" || question.Notes != "wardrobe" {
+			t.Fatal(question, err)
+		}
+
+		created.Question = "LACK"
+		if _, err := repo.UpdateQuestion(*created); !errors.Is(err, quiz.ErrDuplicate) {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.UpdateQuestion(quiz.Question{Id: 42, Question: "APA"}); !errors.Is(err, quiz.ErrUpdateFailed) {
+			t.Fatal(err)
+		}
+
+		all, err := repo.AllQuestions()
+		if err != nil || len(all) != 2 || all[0].Question != "PAX" || all[1].Question != "LACK" {
+			t.Fatal(all, err)
+		}
+	})
+
+	t.Run("RecordAnswer", func(t *testing.T) {
+		repo := newRepository(t)
+
+		question, _ := repo.CreateQuestion(quiz.Question{Question: "PAX", Answer: quiz.Furniture})
+
+		for _, correct := range []bool{true, false, true} {
+			if err := repo.RecordAnswer(question.Id, correct); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		question.Source = "Ikea"
+		if _, err := repo.UpdateQuestion(*question); err != nil {
+			t.Fatal(err)
+		}
+
+		fetched, err := repo.GetQuestionById(question.Id)
+		if err != nil || fetched.TimesServed != 3 || fetched.TimesCorrect != 2 || fetched.Source != "Ikea" {
+			t.Fatal(fetched, err)
+		}
+
+		if err := repo.RecordAnswer(question.Id+1, true); !errors.Is(err, quiz.ErrUpdateFailed) {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("GetQuestionByText_NotExists", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.GetQuestionByText("PAX")
+		if !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("ListQuestions", func(t *testing.T) {
+		repo := newRepository(t)
+
+		repo.CreateQuestion(quiz.Question{Question: "PAX", Answer: quiz.Furniture, Notes: "wardrobe"})
+		repo.CreateQuestion(quiz.Question{Question: "LACK", Answer: quiz.Furniture, Source: "Ikea"})
+		repo.CreateQuestion(quiz.Question{Question: "ZYNGA", Answer: quiz.Fintech})
+		repo.CreateQuestion(quiz.Question{Question: "KALLAX", Answer: quiz.Furniture, Source: "ikea"})
+
+		questions, total, err := repo.ListQuestions("", 1, 2)
+		if err != nil || total != 4 || len(questions) != 2 || questions[0].Question != "LACK" || questions[1].Question != "ZYNGA" {
+			t.Fatal(questions, total, err)
+		}
+
+		questions, total, err = repo.ListQuestions("IKEA", 0, 10)
+		if err != nil || total != 2 || len(questions) != 2 || questions[0].Question != "LACK" || questions[1].Question != "KALLAX" {
+			t.Fatal(questions, total, err)
+		}
+
+		questions, total, err = repo.ListQuestions("robe", 0, 10)
+		if err != nil || total != 1 || questions[0].Question != "PAX" {
+			t.Fatal(questions, total, err)
+		}
+
+		questions, total, err = repo.ListQuestions("", 10, 10)
+		if err != nil || total != 4 || len(questions) != 0 {
+			t.Fatal(questions, total, err)
+		}
+	})
+
+	t.Run("DeleteQuestion", func(t *testing.T) {
+		repo := newRepository(t)
+
+		created, _ := repo.CreateQuestion(quiz.Question{Question: "PAX", Answer: quiz.Furniture})
+
+		if err := repo.DeleteQuestion(created.Id); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.GetQuestionById(created.Id); !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal(err)
+		}
+
+		if err := repo.DeleteQuestion(created.Id); !errors.Is(err, quiz.ErrDeleteFailed) {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("DisabledQuestionsAreNotServed", func(t *testing.T) {
+		repo := newRepository(t)
+
+		pax, _ := repo.CreateQuestion(quiz.Question{Question: "PAX", Answer: quiz.Furniture})
+		repo.CreateQuestion(quiz.Question{Question: "LACK", Answer: quiz.Furniture, Disabled: true})
+
+		game, _ := repo.CreateGame(quiz.NewGame{PlayerName: "bob", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+		unanswered, err := repo.GetUnansweredQuestions(game.Id)
+		if err != nil || len(unanswered) != 1 || unanswered[0].Id != pax.Id {
+			t.Fatal(unanswered, err)
+		}
+
+		lack, _ := repo.GetQuestionByText("LACK")
+		if !lack.Disabled {
+			t.Fatal(lack)
+		}
+	})
+
+	t.Run("AuditEntries", func(t *testing.T) {
+		repo := newRepository(t)
+
+		at := time.Now().UTC().Truncate(time.Second)
+
+		repo.AddAuditEntry(quiz.AuditEntry{Actor: "alice", Action: "create", QuestionId: 1, Question: "PAX", Detail: "answer Furniture", At: at})
+		repo.AddAuditEntry(quiz.AuditEntry{Actor: "bob", Action: "delete", QuestionId: 1, Question: "PAX", At: at.Add(time.Second)})
+
+		entries, err := repo.AuditEntries(10)
+		if err != nil || len(entries) != 2 {
+			t.Fatal(entries, err)
+		}
+
+		if entries[0].Actor != "bob" || entries[1].Actor != "alice" || entries[1].Detail != "answer Furniture" || !entries[1].At.Equal(at) {
+			t.Fatal(entries)
+		}
+
+		entries, err = repo.AuditEntries(1)
+		if err != nil || len(entries) != 1 || entries[0].Actor != "bob" {
+			t.Fatal(entries, err)
+		}
+	})
+
+	t.Run("AuditedChanges", func(t *testing.T) {
+		repo := newRepository(t)
+
+		entry := quiz.AuditEntry{Actor: "alice", Action: "create", At: time.Now()}
+
+		pax, err := repo.CreateQuestionAudited(quiz.Question{Question: "PAX", Answer: quiz.Furniture}, entry)
+		if err != nil || pax.Id == 0 {
+			t.Fatal(pax, err)
+		}
+		zynga, _ := repo.CreateQuestion(quiz.Question{Question: "ZYNGA", Answer: quiz.Fintech})
+
+		entry.Action = "update"
+		renamed := *pax
+		renamed.Question = "ZYNGA"
+		if _, err := repo.UpdateQuestionAudited(renamed, entry); !errors.Is(err, quiz.ErrDuplicate) {
+			t.Fatal(err)
+		}
+
+		renamed.Question = "KALLAX"
+		if _, err := repo.UpdateQuestionAudited(renamed, entry); err != nil {
+			t.Fatal(err)
+		}
+
+		entry.Action = "delete"
+		if err := repo.DeleteQuestionAudited(*zynga, entry); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.DeleteQuestionAudited(*zynga, entry); !errors.Is(err, quiz.ErrDeleteFailed) {
+			t.Fatal(err)
+		}
+
+		entries, err := repo.AuditEntries(10)
+		if err != nil || len(entries) != 3 {
+			t.Fatal("only the changes that happened should be recorded", entries, err)
+		}
+
+		// Newest first, each naming the question as it was changed.
+		if entries[2].Action != "create" || entries[1].Action != "update" || entries[0].Action != "delete" || entries[0].Actor != "alice" {
+			t.Fatal(entries)
+		}
+		if entries[2].Question != "PAX" || entries[1].Question != "KALLAX" || entries[1].QuestionId != pax.Id || entries[0].QuestionId != zynga.Id {
+			t.Fatal(entries)
+		}
+	})
+
+	t.Run("GetQuestionById_NotExists", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.GetQuestionById(42)
+		if !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("CreateGame", func(t *testing.T) {
+		repo := newRepository(t)
+
+		created, err := repo.CreateGame(quiz.NewGame{PlayerName: "bob", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		game, err := repo.GetGameById(created.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if game.PlayerName != "bob" || !game.InProgress || game.Score != 0 || !game.Created.Equal(created.Created) || game.Seed != created.Seed {
+			t.Fatal(game, created)
+		}
+
+		games, err := repo.AllGames()
+		if err != nil || len(games) != 1 || games[0].Id != created.Id {
+			t.Fatal(games, err)
+		}
+
+		if other, _ := repo.CreateGame(quiz.NewGame{PlayerName: "alice", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId}); other.Seed == created.Seed {
+			t.Fatal("games should get their own seeds", other.Seed)
+		}
+
+		owned, err := repo.CreateGame(quiz.NewGame{PlayerName: "carol", Mode: "quick", PackId: quiz.DefaultPackId, PlayerId: 3, SessionId: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if game, err := repo.GetGameById(owned.Id); err != nil || game.Mode != "quick" || game.PlayerId != 3 || game.SessionId != 4 {
+			t.Fatal(game, err)
+		}
+	})
+
+	t.Run("CreateGame_Daily", func(t *testing.T) {
+		repo := newRepository(t)
+
+		bob, err := repo.CreateGame(quiz.NewGame{PlayerName: "bob", Mode: quiz.DailyMode, PackId: quiz.DefaultPackId, ChallengeDay: "2026-10-18"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		game, err := repo.GetGameById(bob.Id)
+		if err != nil || game.Mode != quiz.DailyMode || game.ChallengeDay != "2026-10-18" || game.Seed != quiz.DailySeed("2026-10-18") {
+			t.Fatal(game, err)
+		}
+
+		alice, err := repo.CreateGame(quiz.NewGame{PlayerName: "alice", Mode: quiz.DailyMode, PackId: quiz.DefaultPackId, ChallengeDay: "2026-10-18"})
+		if err != nil || alice.Seed != bob.Seed {
+			t.Fatal(alice, err)
+		}
+
+		if _, err := repo.CreateGame(quiz.NewGame{PlayerName: "BOB", Mode: quiz.DailyMode, PackId: quiz.DefaultPackId, ChallengeDay: "2026-10-18"}); !errors.Is(err, quiz.ErrDuplicate) {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.CreateGame(quiz.NewGame{PlayerName: "bob", Mode: quiz.DailyMode, PackId: quiz.DefaultPackId, ChallengeDay: "2026-10-19"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Rooms", func(t *testing.T) {
+		repo := newRepository(t)
+
+		host, _ := repo.CreateGame(quiz.NewGame{PlayerName: "host", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+		created, err := repo.CreateRoom("ABCDE", quiz.DefaultPackId, host.Id)
+		if err != nil || created.State != quiz.RoomLobby {
+			t.Fatal(created, err)
+		}
+
+		if _, err := repo.CreateRoom("ABCDE", quiz.DefaultPackId, host.Id); !errors.Is(err, quiz.ErrDuplicate) {
+			t.Fatal(err)
+		}
+
+		joined, err := repo.CreateGame(quiz.NewGame{PlayerName: "joiner", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId, RoomCode: "ABCDE"})
+		if err != nil || joined.RoomCode != "ABCDE" {
+			t.Fatal(joined, err)
+		}
+
+		if _, err := repo.CreateGame(quiz.NewGame{PlayerName: "JOINER", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId, RoomCode: "ABCDE"}); !errors.Is(err, quiz.ErrDuplicate) {
+			t.Fatal(err)
+		}
+
+		lobby := *created
+		created.State = quiz.RoomPlaying
+		created.CurrentQuestionId = 7
+		created.QuestionsAsked = 1
+		created.QuestionServed = time.Now().UTC()
+		if _, err := repo.UpdateRoom(created, lobby); err != nil {
+			t.Fatal(err)
+		}
+
+		room, err := repo.GetRoomByCode("ABCDE")
+		if err != nil || !reflect.DeepEqual(*room, *created) {
+			t.Fatal(room, created, err)
+		}
+
+		// Another server moving the room on from the lobby too is refused.
+		stale := lobby
+		stale.State = quiz.RoomPlaying
+		stale.CurrentQuestionId = 8
+		stale.QuestionsAsked = 1
+		if _, err := repo.UpdateRoom(&stale, lobby); !errors.Is(err, quiz.ErrUpdateFailed) {
+			t.Fatal(err)
+		}
+
+		if room, _ := repo.GetRoomByCode("ABCDE"); room.CurrentQuestionId != 7 {
+			t.Fatal(room)
+		}
+
+		if _, err := repo.CreateGame(quiz.NewGame{PlayerName: "late", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId, RoomCode: "ABCDE"}); !errors.Is(err, quiz.ErrRoomStarted) {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.GetRoomByCode("ZZZZZ"); !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.UpdateRoom(&quiz.Room{Code: "ZZZZZ"}, quiz.Room{}); !errors.Is(err, quiz.ErrUpdateFailed) {
+			t.Fatal(err)
+		}
+
+		guest, _ := repo.CreateGame(quiz.NewGame{PlayerName: "guest", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+		repo.CreateGame(quiz.NewGame{PlayerName: "elsewhere", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+		for _, game := range []*quiz.Game{host, guest} {
+			game.RoomCode = "ABCDE"
+			repo.UpdateGame(game)
+		}
+
+		games, err := repo.RoomGames("ABCDE")
+		if err != nil || len(games) != 3 || games[0].Id != host.Id || games[1].Id != joined.Id || games[2].Id != guest.Id || games[2].RoomCode != "ABCDE" {
+			t.Fatal(games, err)
+		}
+	})
+
+	t.Run("Players", func(t *testing.T) {
+		repo := newRepository(t)
+
+		created, err := repo.CreatePlayer("Alice", "hash")
+		if err != nil || created.Id == 0 {
+			t.Fatal(created, err)
+		}
+
+		if _, err := repo.CreatePlayer("alice", "other"); !errors.Is(err, quiz.ErrDuplicate) {
+			t.Fatal(err)
+		}
+
+		player, err := repo.GetPlayerByUsername("ALICE")
+		if err != nil || !reflect.DeepEqual(*player, *created) {
+			t.Fatal(player, created, err)
+		}
+
+		if player, err := repo.GetPlayerById(created.Id); err != nil || player.Username != "Alice" || player.PasswordHash != "hash" {
+			t.Fatal(player, err)
+		}
+
+		if _, err := repo.GetPlayerByUsername("bob"); !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.GetPlayerById(created.Id + 1); !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal(err)
+		}
+
+		first, _ := repo.CreateGame(quiz.NewGame{PlayerName: "Alice", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+		second, _ := repo.CreateGame(quiz.NewGame{PlayerName: "Alice", Mode: "quick", PackId: quiz.DefaultPackId})
+		repo.CreateGame(quiz.NewGame{PlayerName: "guest", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+		for _, game := range []*quiz.Game{first, second} {
+			game.PlayerId = created.Id
+			repo.UpdateGame(game)
+		}
+
+		games, err := repo.PlayerGames(created.Id)
+		if err != nil || len(games) != 2 || games[0].Id != second.Id || games[1].Id != first.Id || games[0].PlayerId != created.Id {
+			t.Fatal(games, err)
+		}
+	})
+
+	t.Run("Sessions", func(t *testing.T) {
+		repo := newRepository(t)
+
+		expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+		created, err := repo.CreateSession(7, expires)
+		if err != nil || len(created.Token) < 32 {
+			t.Fatal(created, err)
+		}
+
+		if other, _ := repo.CreateSession(7, expires); other.Token == created.Token {
+			t.Fatal("sessions should get their own tokens")
+		}
+
+		session, err := repo.GetSession(created.Token)
+		if err != nil || session.PlayerId != 7 || !session.Expires.Equal(expires) || !session.Created.Equal(created.Created) {
+			t.Fatal(session, created, err)
+		}
+
+		if err := repo.DeleteSession(created.Token); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.GetSession(created.Token); !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal(err)
+		}
+
+		if err := repo.DeleteSession(created.Token); !errors.Is(err, quiz.ErrDeleteFailed) {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("GameSessions", func(t *testing.T) {
+		repo := newRepository(t)
+
+		expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+		created, err := repo.CreateGameSession(expires)
+		if err != nil || created.Id == 0 || len(created.Token) < 32 {
+			t.Fatal(created, err)
+		}
+
+		other, _ := repo.CreateGameSession(expires)
+		if other.Id == created.Id || other.Token == created.Token {
+			t.Fatal("sessions should get their own ids and tokens")
+		}
+
+		first, _ := repo.CreateGame(quiz.NewGame{PlayerName: "bob", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+		first.SessionId = created.Id
+		repo.UpdateGame(first)
+		time.Sleep(time.Millisecond)
+		second, _ := repo.CreateGame(quiz.NewGame{PlayerName: "bob", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+		second.SessionId = created.Id
+		repo.UpdateGame(second)
+		repo.CreateGame(quiz.NewGame{PlayerName: "carol", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+		later := expires.Add(time.Hour)
+		rotated, err := repo.RotateGameSession(created.Id, later)
+		if err != nil || rotated.Id != created.Id || rotated.Token == created.Token || !rotated.Expires.Equal(later) {
+			t.Fatal(rotated, err)
+		}
+
+		if _, err := repo.GetGameSession(created.Token); !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal("the old token should stop working", err)
+		}
+
+		session, err := repo.GetGameSession(rotated.Token)
+		if err != nil || session.Id != created.Id || !session.Created.Equal(created.Created) {
+			t.Fatal(session, created, err)
+		}
+
+		newest, err := repo.GetSessionGame(session.Id, uuid.Nil)
+		if err != nil || newest.Id != second.Id {
+			t.Fatal(newest, err)
+		}
+
+		game, err := repo.GetSessionGame(session.Id, first.Id)
+		if err != nil || game.Id != first.Id {
+			t.Fatal(game, err)
+		}
+
+		if _, err := repo.GetSessionGame(other.Id, first.Id); !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal("another session's game should not be found", err)
+		}
+
+		if err := repo.DeleteGameSession(rotated.Token); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.GetGameSession(rotated.Token); !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal(err)
+		}
+
+		if err := repo.DeleteGameSession(rotated.Token); !errors.Is(err, quiz.ErrDeleteFailed) {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.RotateGameSession(created.Id, later); !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("PingAndClose", func(t *testing.T) {
+		repo := newRepository(t)
+
+		if err := repo.Ping(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.Ping(context.Background()); err == nil {
+			t.Fatal("ping should fail once the repository is closed")
+		}
+	})
+
+	t.Run("GetGameById_NotExists", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.GetGameById(uuid.New())
+		if !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("UpdateGame", func(t *testing.T) {
+		repo := newRepository(t)
+
+		game, _ := repo.CreateGame(quiz.NewGame{PlayerName: "bob", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+		game.QuestionsAnswered = 10
+		game.Score = 7
+		game.InProgress = false
+		game.Completed = time.Now().UTC().Truncate(time.Second)
+		game.CurrentQuestionId = 3
+		game.Mistakes = 2
+		game.QuestionServed = time.Now().UTC().Truncate(time.Second)
+		game.Seed = -42
+
+		if _, err := repo.UpdateGame(game); err != nil {
+			t.Fatal(err)
+		}
+
+		updated, err := repo.GetGameById(game.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if updated.QuestionsAnswered != 10 || updated.Score != 7 || updated.InProgress || !updated.Completed.Equal(game.Completed) || updated.CurrentQuestionId != 3 || updated.Mistakes != 2 || !updated.QuestionServed.Equal(game.QuestionServed) || updated.Seed != -42 {
+			t.Fatal(updated, game)
+		}
+	})
+
+	t.Run("UpdateGame_NotExists", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.UpdateGame(&quiz.Game{Id: uuid.New(), PlayerName: "bob"})
+		if !errors.Is(err, quiz.ErrUpdateFailed) {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("GameQuestions", func(t *testing.T) {
+		repo := newRepository(t)
+
+		pax, _ := repo.CreateQuestion(quiz.Question{Question: "PAX", Answer: quiz.Furniture})
+		zynga, _ := repo.CreateQuestion(quiz.Question{Question: "ZYNGA", Answer: quiz.Fintech})
+
+		game, _ := repo.CreateGame(quiz.NewGame{PlayerName: "bob", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+		other, _ := repo.CreateGame(quiz.NewGame{PlayerName: "alice", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+		if err := repo.AddGameQuestion(game.Id, pax.Id); err != nil {
+			t.Fatal(err)
+		}
+
+		unanswered, err := repo.GetUnansweredQuestions(game.Id)
+		if err != nil || len(unanswered) != 1 || unanswered[0].Id != zynga.Id {
+			t.Fatal(unanswered, err)
+		}
+
+		unanswered, err = repo.GetUnansweredQuestions(other.Id)
+		if err != nil || len(unanswered) != 2 {
+			t.Fatal(unanswered, err)
+		}
+
+		if err := repo.RemoveGameQuestions(game.Id); err != nil {
+			t.Fatal(err)
+		}
+
+		unanswered, err = repo.GetUnansweredQuestions(game.Id)
+		if err != nil || len(unanswered) != 2 {
+			t.Fatal(unanswered, err)
+		}
+	})
+
+	t.Run("Packs", func(t *testing.T) {
+		repo := newRepository(t)
+
+		defaultPack, err := repo.GetPackById(quiz.DefaultPackId)
+		if err != nil || !reflect.DeepEqual(*defaultPack, quiz.DefaultPack) {
+			t.Fatal(defaultPack, err)
+		}
+
+		created, err := repo.CreatePack(quiz.Pack{Slug: "crypto-cheese-or-cars", Name: "Crypto, Cheese or Cars", Options: []quiz.AnswerOption{{Label: "Crypto"}, {Label: "Cheese"}, {Label: "Cars"}}})
+		if err != nil || created.Id == quiz.DefaultPackId || len(created.Options) != 3 || created.Options[2].Label != "Cars" {
+			t.Fatal(created, err)
+		}
+
+		seen := map[quiz.Answer]bool{quiz.Fintech: true, quiz.Furniture: true}
+		for _, option := range created.Options {
+			if option.Id == 0 || seen[option.Id] {
+				t.Fatal(created.Options)
+			}
+			seen[option.Id] = true
+		}
+
+		if _, err := repo.CreatePack(quiz.Pack{Slug: "crypto-cheese-or-cars", Name: "Again", Options: []quiz.AnswerOption{{Label: "A"}, {Label: "B"}}}); !errors.Is(err, quiz.ErrDuplicate) {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.CreatePack(quiz.Pack{Slug: "twice", Name: "Twice", Options: []quiz.AnswerOption{{Label: "A"}, {Label: "A"}}}); !errors.Is(err, quiz.ErrDuplicate) {
+			t.Fatal(err)
+		}
+
+		if _, err := repo.GetPackBySlug("twice"); !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal(err)
+		}
+
+		bySlug, err := repo.GetPackBySlug("crypto-cheese-or-cars")
+		if err != nil || !reflect.DeepEqual(*bySlug, *created) {
+			t.Fatal(bySlug, err)
+		}
+
+		if _, err := repo.GetPackBySlug("nonsense"); !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal(err)
+		}
+
+		packs, err := repo.AllPacks()
+		if err != nil || len(packs) != 2 || !reflect.DeepEqual(packs[1], *created) {
+			t.Fatal(packs, err)
+		}
+	})
+
+	t.Run("UpdatePack", func(t *testing.T) {
+		repo := newRepository(t)
+
+		created, _ := repo.CreatePack(quiz.Pack{Slug: "fintech-or-pharma", Name: "Fintech or Pharma", Options: []quiz.AnswerOption{{Label: "Fintech"}, {Label: "Pharma"}}})
+
+		update := *created
+		update.Name = "Fintech, Pharma or Pokémon"
+		update.Options = append(update.Options, quiz.AnswerOption{Label: "Pokémon"}, quiz.AnswerOption{Label: "Cheese"})
+
+		updated, err := repo.UpdatePack(update)
+		if err != nil || updated.Name != update.Name || len(updated.Options) != 4 || updated.Options[0] != created.Options[0] || updated.Options[2].Label != "Pokémon" {
+			t.Fatal(updated, err)
+		}
+
+		seen := map[quiz.Answer]bool{quiz.Fintech: true, quiz.Furniture: true}
+		for _, option := range updated.Options {
+			if option.Id == 0 || seen[option.Id] {
+				t.Fatal(updated.Options)
+			}
+			seen[option.Id] = true
+		}
+
+		fetched, err := repo.GetPackBySlug("fintech-or-pharma")
+		if err != nil || !reflect.DeepEqual(*fetched, *updated) {
+			t.Fatal(fetched, err)
+		}
+
+		// Options left out are kept.
+		update = *fetched
+		update.Options = []quiz.AnswerOption{{Label: "Cars"}}
+		updated, err = repo.UpdatePack(update)
+		if err != nil || len(updated.Options) != 5 || updated.Options[4].Label != "Cars" {
+			t.Fatal(updated, err)
+		}
+
+		update.Options = []quiz.AnswerOption{{Label: "Pharma"}}
+		if _, err := repo.UpdatePack(update); !errors.Is(err, quiz.ErrDuplicate) {
+			t.Fatal(err)
+		}
+
+		update.Id = 99
+		if _, err := repo.UpdatePack(update); !errors.Is(err, quiz.ErrNotExists) {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("GameQuestions_StatisticsSnapshot", func(t *testing.T) {
+		repo := newRepository(t)
+
+		pax, _ := repo.CreateQuestion(quiz.Question{Question: "PAX", Answer: quiz.Furniture})
+		repo.CreateQuestion(quiz.Question{Question: "ZYNGA", Answer: quiz.Fintech})
+		repo.RecordAnswer(pax.Id, true)
+		repo.RecordAnswer(pax.Id, false)
+
+		game, _ := repo.CreateGame(quiz.NewGame{PlayerName: "bob", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+		daily, _ := repo.CreateGame(quiz.NewGame{PlayerName: "bob", Mode: quiz.DailyMode, PackId: quiz.DefaultPackId, ChallengeDay: "2026-10-18"})
+
+		repo.RecordAnswer(pax.Id, false)
+
+		for _, id := range []uuid.UUID{game.Id, daily.Id} {
+			unanswered, err := repo.GetUnansweredQuestions(id)
+			if err != nil || len(unanswered) != 2 || unanswered[0].TimesServed != 2 || unanswered[0].TimesCorrect != 1 || unanswered[1].TimesServed != 0 {
+				t.Fatal("games should see the statistics from when they were created", unanswered, err)
+			}
+		}
+
+		later, _ := repo.CreateGame(quiz.NewGame{PlayerName: "carol", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+		unanswered, err := repo.GetUnansweredQuestions(later.Id)
+		if err != nil || unanswered[0].TimesServed != 3 || unanswered[0].TimesCorrect != 1 {
+			t.Fatal(unanswered, err)
+		}
+	})
+
+	t.Run("GameQuestions_OnlyFromGamePack", func(t *testing.T) {
+		repo := newRepository(t)
+
+		cheese, _ := repo.CreatePack(quiz.Pack{Slug: "crypto-or-cheese", Name: "Crypto or Cheese", Options: []quiz.AnswerOption{{Label: "Crypto"}, {Label: "Cheese"}}})
+
+		repo.CreateQuestion(quiz.Question{Question: "PAX", Answer: quiz.Furniture})
+		brie, _ := repo.CreateQuestion(quiz.Question{Question: "BRIE", Answer: cheese.Options[1].Id, PackId: cheese.Id})
+
+		game, _ := repo.CreateGame(quiz.NewGame{PlayerName: "bob", Mode: quiz.DefaultMode, PackId: cheese.Id})
+
+		unanswered, err := repo.GetUnansweredQuestions(game.Id)
+		if err != nil || len(unanswered) != 1 || unanswered[0].Id != brie.Id || unanswered[0].PackId != cheese.Id {
+			t.Fatal(unanswered, err)
+		}
+
+		count, err := repo.CountEnabledQuestions(cheese.Id)
+		if err != nil || count != 1 {
+			t.Fatal(count, err)
+		}
+
+		fetched, _ := repo.GetGameById(game.Id)
+		if fetched.PackId != cheese.Id {
+			t.Fatal(fetched)
+		}
+	})
+
+	t.Run("ClaimCurrentQuestion", func(t *testing.T) {
+		repo := newRepository(t)
+
+		game, _ := repo.CreateGame(quiz.NewGame{PlayerName: "bob", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+
+		if err := repo.ClaimCurrentQuestion(game.Id, 1); !errors.Is(err, quiz.ErrUpdateFailed) {
+			t.Fatal(err)
+		}
+
+		game.CurrentQuestionId = 1
+		repo.UpdateGame(game)
+
+		if err := repo.ClaimCurrentQuestion(game.Id, 2); !errors.Is(err, quiz.ErrUpdateFailed) {
+			t.Fatal(err)
+		}
+
+		if err := repo.ClaimCurrentQuestion(game.Id, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.ClaimCurrentQuestion(game.Id, 1); !errors.Is(err, quiz.ErrUpdateFailed) {
+			t.Fatal(err)
+		}
+
+		claimed, _ := repo.GetGameById(game.Id)
+		if claimed.CurrentQuestionId != 0 {
+			t.Fatal(claimed)
+		}
+	})
+
+	t.Run("TopTenCompletedGames", func(t *testing.T) {
+		repo := newRepository(t)
+
+		now := time.Now().UTC()
+
+		for i := 0; i < 12; i++ {
+			game, _ := repo.CreateGame(quiz.NewGame{PlayerName: "player", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+			game.Score = int64(i)
+			game.QuestionsAnswered = 10
+			game.InProgress = false
+			game.Completed = now
+			repo.UpdateGame(game)
+		}
+
+		old, _ := repo.CreateGame(quiz.NewGame{PlayerName: "old", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+		old.Score = 100
+		old.InProgress = false
+		old.Completed = now.AddDate(0, 0, -2)
+		repo.UpdateGame(old)
+
+		unfinished, _ := repo.CreateGame(quiz.NewGame{PlayerName: "unfinished", Mode: quiz.DefaultMode, PackId: quiz.DefaultPackId})
+		unfinished.Score = 100
+		repo.UpdateGame(unfinished)
+
+		quick, _ := repo.CreateGame(quiz.NewGame{PlayerName: "quick", Mode: "quick", PackId: quiz.DefaultPackId})
+		quick.Score = 100
+		quick.InProgress = false
+		quick.Completed = now
+		repo.UpdateGame(quick)
+
+		games, err := repo.TopTenCompletedGames(quiz.DefaultMode, now.AddDate(0, 0, -1))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(games) != 10 || games[0].Score != 11 || games[9].Score != 2 {
+			t.Fatal(games)
+		}
+
+		games, err = repo.TopTenCompletedGames("quick", now.AddDate(0, 0, -1))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(games) != 1 || games[0].PlayerName != "quick" || games[0].Mode != "quick" {
+			t.Fatal(games)
+		}
+	})
+
+	t.Run("TopTenDailyGames", func(t *testing.T) {
+		repo := newRepository(t)
+
+		for i, name := range []string{"bob", "alice", "carol"} {
+			game, _ := repo.CreateGame(quiz.NewGame{PlayerName: name, Mode: quiz.DailyMode, PackId: quiz.DefaultPackId, ChallengeDay: "2026-10-18"})
+			game.Score = int64(i)
+			game.QuestionsAnswered = 10
+			game.InProgress = false
+			game.Completed = time.Now().UTC()
+			repo.UpdateGame(game)
+		}
+
+		unfinished, _ := repo.CreateGame(quiz.NewGame{PlayerName: "dave", Mode: quiz.DailyMode, PackId: quiz.DefaultPackId, ChallengeDay: "2026-10-18"})
+		unfinished.Score = 100
+		repo.UpdateGame(unfinished)
+
+		yesterday, _ := repo.CreateGame(quiz.NewGame{PlayerName: "erin", Mode: quiz.DailyMode, PackId: quiz.DefaultPackId, ChallengeDay: "2026-10-17"})
+		yesterday.Score = 100
+		yesterday.InProgress = false
+		yesterday.Completed = time.Now().UTC()
+		repo.UpdateGame(yesterday)
+
+		games, err := repo.TopTenDailyGames("2026-10-18")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(games) != 3 || games[0].PlayerName != "carol" || games[2].PlayerName != "bob" {
+			t.Fatal(games)
+		}
+	})
+
+	t.Run("TopTenStreaks", func(t *testing.T) {
+		repo := newRepository(t)
+
+		now := time.Now().UTC()
+
+		for i := 0; i < 3; i++ {
+			game, _ := repo.CreateGame(quiz.NewGame{PlayerName: "player", Mode: "survival", PackId: quiz.DefaultPackId})
+			game.Score = int64(10 - i)
+			game.BestStreak = int64(i)
+			game.InProgress = false
+			game.Completed = now
+			repo.UpdateGame(game)
+		}
+
+		games, err := repo.TopTenStreaks("survival", now.AddDate(0, 0, -1))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(games) != 3 || games[0].BestStreak != 2 || games[2].BestStreak != 0 {
+			t.Fatal(games)
+		}
+	})
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	testRepository(t, func(t *testing.T) quiz.Repository {
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		repo := NewSQLiteRepository(db)
+		if err := repo.Migrate(); err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
+
+// TestSQLiteRepository_AuditedChangeRollsBack checks that a change whose
+// audit entry cannot be written is not made either.
+func TestSQLiteRepository_AuditedChangeRollsBack(t *testing.T) {
+	db := openTestSQLite(t)
+
+	repo := NewSQLiteRepository(db)
+	if err := repo.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec("DROP TABLE questionAudit"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateQuestionAudited(quiz.Question{Question: "PAX", Answer: quiz.Furniture}, quiz.AuditEntry{Actor: "alice", Action: "create"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if _, err := repo.GetQuestionByText("PAX"); !errors.Is(err, quiz.ErrNotExists) {
+		t.Fatal("the question should not have been created", err)
+	}
+}
+
+func TestMemoryRepository(t *testing.T) {
+	testRepository(t, func(t *testing.T) quiz.Repository {
+		return NewMemoryRepository()
+	})
+}
+
+// TestPostgresRepository runs against the database in FOF_TEST_POSTGRES_DSN,
+// dropping its tables first, and is skipped when that is not set.
+func TestPostgresRepository(t *testing.T) {
+	dsn := os.Getenv("FOF_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("FOF_TEST_POSTGRES_DSN not set")
+	}
+
+	testRepository(t, func(t *testing.T) quiz.Repository {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		if _, err := db.Exec("DROP TABLE IF EXISTS questions, games, gameQuestions, questionAudit, schema_migrations"); err != nil {
+			t.Fatal(err)
+		}
+
+		repo := NewPostgresRepository(db)
+		if err := repo.Migrate(); err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
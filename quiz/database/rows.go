@@ -0,0 +1,338 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"me885/fintech-or-furniture/quiz"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// execer runs queries on either the database or a transaction, for
+// statements that are made both on their own and as part of a larger change.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// newGame is the game CreateGame starts from start, created at created.
+func newGame(start quiz.NewGame, created time.Time) quiz.Game {
+	game := quiz.Game{
+		Id:           uuid.New(),
+		PlayerName:   start.PlayerName,
+		InProgress:   true,
+		Created:      created,
+		Mode:         start.Mode,
+		PackId:       start.PackId,
+		Seed:         rand.Int63(),
+		ChallengeDay: start.ChallengeDay,
+		RoomCode:     start.RoomCode,
+		PlayerId:     start.PlayerId,
+		SessionId:    start.SessionId,
+	}
+
+	if start.ChallengeDay != "" {
+		game.Seed = quiz.DailySeed(start.ChallengeDay)
+	}
+
+	return game
+}
+
+// checkRoomLobby returns quiz.ErrRoomStarted unless the room query reads the
+// state of, given its code, is still in the lobby.
+func checkRoomLobby(tx execer, query string, code string) error {
+	var state quiz.RoomState
+	err := tx.QueryRow(query, code).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotExists
+	}
+	if err != nil {
+		return err
+	}
+
+	if state != quiz.RoomLobby {
+		return quiz.ErrRoomStarted
+	}
+	return nil
+}
+
+// inTransaction runs change in a transaction, which it commits if change
+// succeeds and rolls back otherwise.
+func inTransaction(db *sql.DB, change func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := change(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// questionColumns is the column list scanQuestion expects, shared by the SQL
+// repositories.
+const questionColumns = "id, question, answer, source, notes, disabled, packId, timesServed, timesCorrect"
+
+// snapshotQuestionColumns is questionColumns for questions q with the
+// statistics in the gameQuestionStats s snapshot, or none if it has no row.
+const snapshotQuestionColumns = "q.id, q.question, q.answer, q.source, q.notes, q.disabled, q.packId, COALESCE(s.timesServed, 0), COALESCE(s.timesCorrect, 0)"
+
+// gameColumns is the column list scanGame expects.
+const gameColumns = "id, playerName, questionsAnswered, score, inProgress, created, completed, currentQuestionId, mode, mistakes, questionServed, streak, bestStreak, outOfQuestions, packId, seed, challengeDay, roomCode, playerId, sessionId"
+
+// roomColumns is the column list scanRoom expects.
+const roomColumns = "code, packId, hostGameId, state, currentQuestionId, questionsAsked, seed, created, questionServed"
+
+// playerColumns is the column list scanPlayer expects.
+const playerColumns = "id, username, passwordHash, created"
+
+// sessionColumns is the column list scanSession expects.
+const sessionColumns = "token, playerId, created, expires"
+
+// gameSessionColumns is the column list scanGameSession expects.
+const gameSessionColumns = "id, token, created, expires"
+
+// packColumns is the column list scanPack expects. A pack's options are
+// loaded separately by loadOptions.
+const packColumns = "id, slug, name"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanQuestion(row rowScanner) (*quiz.Question, error) {
+	var question quiz.Question
+	if err := row.Scan(&question.Id, &question.Question, &question.Answer, &question.Source, &question.Notes, &question.Disabled, &question.PackId, &question.TimesServed, &question.TimesCorrect); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotExists
+		}
+		return nil, err
+	}
+	return &question, nil
+}
+
+func scanQuestions(rows *sql.Rows) ([]quiz.Question, error) {
+	defer rows.Close()
+
+	var all []quiz.Question
+	for rows.Next() {
+		question, err := scanQuestion(rows)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, *question)
+	}
+	return all, rows.Err()
+}
+
+func scanPack(row rowScanner) (*quiz.Pack, error) {
+	var pack quiz.Pack
+	if err := row.Scan(&pack.Id, &pack.Slug, &pack.Name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotExists
+		}
+		return nil, err
+	}
+	return &pack, nil
+}
+
+func scanPacks(rows *sql.Rows) ([]quiz.Pack, error) {
+	defer rows.Close()
+
+	var all []quiz.Pack
+	for rows.Next() {
+		pack, err := scanPack(rows)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, *pack)
+	}
+	return all, rows.Err()
+}
+
+// loadOptions fills in the answer options of each pack, in order. The query
+// has no parameters, so the SQL repositories share it.
+func loadOptions(db *sql.DB, packs []quiz.Pack) error {
+	rows, err := db.Query("SELECT packId, id, label FROM answerOptions ORDER BY packId, position")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	options := map[int64][]quiz.AnswerOption{}
+	for rows.Next() {
+		var packId int64
+		var option quiz.AnswerOption
+		if err := rows.Scan(&packId, &option.Id, &option.Label); err != nil {
+			return err
+		}
+		options[packId] = append(options[packId], option)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range packs {
+		packs[i].Options = options[packs[i].Id]
+	}
+	return nil
+}
+
+func scanAuditEntries(rows *sql.Rows) ([]quiz.AuditEntry, error) {
+	defer rows.Close()
+
+	var all []quiz.AuditEntry
+	for rows.Next() {
+		var entry quiz.AuditEntry
+		if err := rows.Scan(&entry.Id, &entry.Actor, &entry.Action, &entry.QuestionId, &entry.Question, &entry.Detail, &entry.At); err != nil {
+			return nil, err
+		}
+		all = append(all, entry)
+	}
+	return all, rows.Err()
+}
+
+func scanGame(row rowScanner) (*quiz.Game, error) {
+	var game quiz.Game
+	if err := row.Scan(
+		&game.Id,
+		&game.PlayerName,
+		&game.QuestionsAnswered,
+		&game.Score,
+		&game.InProgress,
+		timeColumn{&game.Created},
+		timeColumn{&game.Completed},
+		&game.CurrentQuestionId,
+		&game.Mode,
+		&game.Mistakes,
+		timeColumn{&game.QuestionServed},
+		&game.Streak,
+		&game.BestStreak,
+		&game.OutOfQuestions,
+		&game.PackId,
+		&game.Seed,
+		&game.ChallengeDay,
+		&game.RoomCode,
+		&game.PlayerId,
+		&game.SessionId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotExists
+		}
+		return nil, err
+	}
+	return &game, nil
+}
+
+func scanGames(rows *sql.Rows) ([]quiz.Game, error) {
+	defer rows.Close()
+
+	var all []quiz.Game
+	for rows.Next() {
+		game, err := scanGame(rows)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, *game)
+	}
+	return all, rows.Err()
+}
+
+func scanRoom(row rowScanner) (*quiz.Room, error) {
+	var room quiz.Room
+	if err := row.Scan(
+		&room.Code,
+		&room.PackId,
+		&room.HostGameId,
+		&room.State,
+		&room.CurrentQuestionId,
+		&room.QuestionsAsked,
+		&room.Seed,
+		timeColumn{&room.Created},
+		timeColumn{&room.QuestionServed}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotExists
+		}
+		return nil, err
+	}
+	return &room, nil
+}
+
+func scanPlayer(row rowScanner) (*quiz.Player, error) {
+	var player quiz.Player
+	if err := row.Scan(&player.Id, &player.Username, &player.PasswordHash, timeColumn{&player.Created}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotExists
+		}
+		return nil, err
+	}
+	return &player, nil
+}
+
+func scanSession(row rowScanner) (*quiz.Session, error) {
+	var session quiz.Session
+	if err := row.Scan(&session.Token, &session.PlayerId, timeColumn{&session.Created}, timeColumn{&session.Expires}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotExists
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func scanGameSession(row rowScanner) (*quiz.GameSession, error) {
+	var session quiz.GameSession
+	if err := row.Scan(&session.Id, &session.Token, timeColumn{&session.Created}, timeColumn{&session.Expires}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotExists
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// timeColumn scans both native timestamps and the text SQLite stores them
+// as, leaving the zero time for NULL.
+type timeColumn struct {
+	t *time.Time
+}
+
+func (c timeColumn) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*c.t = time.Time{}
+		return nil
+	case time.Time:
+		*c.t = value.UTC()
+		return nil
+	case []byte:
+		return c.parse(string(value))
+	case string:
+		return c.parse(value)
+	}
+
+	return fmt.Errorf("cannot scan %T into a time", src)
+}
+
+func (c timeColumn) parse(value string) error {
+	parsed, err := time.Parse("2006-01-02 15:04:05.999999999-07:00", value)
+	if err != nil {
+		return err
+	}
+
+	*c.t = parsed.UTC()
+	return nil
+}
+
+// boolToInt is for adding a bool to a count in SQL.
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -1,26 +1,30 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"math/rand"
 	"me885/fintech-or-furniture/quiz"
 	"time"
 
 	"github.com/google/uuid"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 var (
-	ErrDuplicate    = errors.New("record already exists")
-	ErrNotExists    = errors.New("row not exists")
-	ErrUpdateFailed = errors.New("update failed")
-	ErrDeleteFailed = errors.New("delete failed")
+	ErrDuplicate    = quiz.ErrDuplicate
+	ErrNotExists    = quiz.ErrNotExists
+	ErrUpdateFailed = quiz.ErrUpdateFailed
+	ErrDeleteFailed = quiz.ErrDeleteFailed
 )
 
 type SQLiteRepository struct {
 	db *sql.DB
 }
 
+var _ quiz.Repository = (*SQLiteRepository)(nil)
+
 func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
 	return &SQLiteRepository{
 		db: db,
@@ -28,7 +32,20 @@ func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
 }
 
 func (r *SQLiteRepository) Migrate() error {
-	query := `--sql
+	_, err := runMigrations(r.db, sqliteMigrationDialect, sqliteMigrations)
+	return err
+}
+
+func (r *SQLiteRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+var sqliteMigrations = []migration{
+	{1, "create questions, games and gameQuestions", execMigration(`--sql
 
     CREATE TABLE IF NOT EXISTS questions(
         id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -51,18 +68,348 @@ func (r *SQLiteRepository) Migrate() error {
 		gameId BLOB NOT NULL,
 		QuestionId INTEGER NOT NULL
 	);
-    `
+    `)},
+	{2, "add games.currentQuestionId", addSQLiteColumn("games", "currentQuestionId", "INTEGER NOT NULL DEFAULT 0")},
+	{3, "add questions.source and questions.notes", execMigration(`--sql
+	ALTER TABLE questions ADD COLUMN source TEXT NOT NULL DEFAULT '';
+	ALTER TABLE questions ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+	`)},
+	{4, "add questions.disabled and questionAudit", execMigration(`--sql
+	ALTER TABLE questions ADD COLUMN disabled INTEGER NOT NULL DEFAULT 0;
+
+	CREATE TABLE questionAudit(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		questionId INTEGER NOT NULL,
+		question TEXT NOT NULL,
+		detail TEXT NOT NULL,
+		at TIMESTAMP NOT NULL
+	);
+	`)},
+	{5, "add games.mode and games.mistakes", execMigration(`--sql
+	ALTER TABLE games ADD COLUMN mode TEXT NOT NULL DEFAULT 'classic';
+	ALTER TABLE games ADD COLUMN mistakes INTEGER NOT NULL DEFAULT 0;
+	`)},
+	{6, "add games.questionServed", execMigration(`--sql
+	ALTER TABLE games ADD COLUMN questionServed BLOB;
+	`)},
+	{7, "add games.streak and games.bestStreak", execMigration(`--sql
+	ALTER TABLE games ADD COLUMN streak INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE games ADD COLUMN bestStreak INTEGER NOT NULL DEFAULT 0;
+	`)},
+	{8, "add games.outOfQuestions", execMigration(`--sql
+	ALTER TABLE games ADD COLUMN outOfQuestions INTEGER NOT NULL DEFAULT 0;
+	`)},
+	{9, "add packs, questions.packId and games.packId", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`--sql
+		CREATE TABLE packs(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			slug TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL,
+			firstLabel TEXT NOT NULL,
+			secondLabel TEXT NOT NULL
+		);
+
+		ALTER TABLE questions ADD COLUMN packId INTEGER NOT NULL DEFAULT 1;
+		ALTER TABLE games ADD COLUMN packId INTEGER NOT NULL DEFAULT 1;
+		`)
+		if err != nil {
+			return err
+		}
+
+		return insertDefaultPack(tx, "INSERT INTO packs(id, slug, name, firstLabel, secondLabel) values(?,?,?,?,?)")
+	}},
+	{10, "move pack labels to answerOptions", execMigration(`--sql
+	CREATE TABLE answerOptions(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		packId INTEGER NOT NULL,
+		position INTEGER NOT NULL,
+		label TEXT NOT NULL,
+		UNIQUE(packId, position),
+		UNIQUE(packId, label)
+	);
+	` + moveLabelsToAnswerOptions)},
+	{11, "add questions.timesServed and questions.timesCorrect", execMigration(`--sql
+	ALTER TABLE questions ADD COLUMN timesServed INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE questions ADD COLUMN timesCorrect INTEGER NOT NULL DEFAULT 0;
+	`)},
+	{12, "add games.seed", execMigration(`--sql
+	ALTER TABLE games ADD COLUMN seed INTEGER NOT NULL DEFAULT 0;
+	`)},
+	{13, "add games.challengeDay", execMigration(`--sql
+	ALTER TABLE games ADD COLUMN challengeDay TEXT NOT NULL DEFAULT '';
+
+	CREATE UNIQUE INDEX games_challengeDay_playerName ON games(challengeDay, lower(playerName)) WHERE challengeDay != '';
+	`)},
+	{14, "add rooms and games.roomCode", execMigration(`--sql
+	CREATE TABLE rooms(
+		code TEXT PRIMARY KEY,
+		packId INTEGER NOT NULL,
+		hostGameId BLOB NOT NULL,
+		state TEXT NOT NULL,
+		currentQuestionId INTEGER NOT NULL DEFAULT 0,
+		questionsAsked INTEGER NOT NULL DEFAULT 0,
+		seed INTEGER NOT NULL,
+		created BLOB NOT NULL
+	);
+
+	ALTER TABLE games ADD COLUMN roomCode TEXT NOT NULL DEFAULT '';
+
+	CREATE INDEX games_roomCode ON games(roomCode) WHERE roomCode != '';
+	`)},
+	{15, "add players, sessions and games.playerId", execMigration(`--sql
+	CREATE TABLE players(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL,
+		passwordHash TEXT NOT NULL,
+		created BLOB NOT NULL
+	);
+
+	CREATE UNIQUE INDEX players_username ON players(lower(username));
+
+	CREATE TABLE sessions(
+		token TEXT PRIMARY KEY,
+		playerId INTEGER NOT NULL,
+		created BLOB NOT NULL,
+		expires BLOB NOT NULL
+	);
 
-	_, err := r.db.Exec(query)
+	ALTER TABLE games ADD COLUMN playerId INTEGER NOT NULL DEFAULT 0;
+
+	CREATE INDEX games_playerId ON games(playerId) WHERE playerId != 0;
+	`)},
+	{16, "add gameSessions and games.sessionId", execMigration(`--sql
+	CREATE TABLE gameSessions(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		token TEXT NOT NULL,
+		created BLOB NOT NULL,
+		expires BLOB NOT NULL
+	);
+
+	CREATE UNIQUE INDEX gameSessions_token ON gameSessions(token);
+
+	ALTER TABLE games ADD COLUMN sessionId INTEGER NOT NULL DEFAULT 0;
+
+	CREATE INDEX games_sessionId ON games(sessionId) WHERE sessionId != 0;
+	`)},
+	{17, "add gameQuestionStats", execMigration(`--sql
+	CREATE TABLE gameQuestionStats(
+		gameId BLOB NOT NULL,
+		questionId INTEGER NOT NULL,
+		timesServed INTEGER NOT NULL,
+		timesCorrect INTEGER NOT NULL,
+		PRIMARY KEY(gameId, questionId)
+	);
+	`)},
+	{18, "add rooms.questionServed", execMigration(`--sql
+	ALTER TABLE rooms ADD COLUMN questionServed BLOB;
+	`)},
+	{19, "add games_roomCode_playerName index", execMigration(`--sql
+	CREATE UNIQUE INDEX games_roomCode_playerName ON games(roomCode, lower(playerName)) WHERE roomCode != '';
+	`)},
+}
+
+// insertDefaultPack adds the pack that existing questions and games are
+// moved into, with the id the column defaults point at.
+func insertDefaultPack(tx *sql.Tx, insert string) error {
+	pack := quiz.DefaultPack
+	_, err := tx.Exec(insert, pack.Id, pack.Slug, pack.Name, pack.Options[0].Label, pack.Options[1].Label)
 	return err
 }
 
+// moveLabelsToAnswerOptions turns each pack's two label columns into
+// answerOptions rows and questions.answer, until then an index into the
+// labels, into an option id. Options are numbered in pack order, so the
+// default pack's become quiz.Fintech and quiz.Furniture.
+const moveLabelsToAnswerOptions = `--sql
+	INSERT INTO answerOptions(packId, position, label)
+	SELECT id, 0, firstLabel FROM packs
+	UNION ALL
+	SELECT id, 1, secondLabel FROM packs
+	ORDER BY 1, 2;
+
+	UPDATE questions SET answer = (
+		SELECT id
+		FROM answerOptions
+		WHERE answerOptions.packId = questions.packId AND answerOptions.position = questions.answer
+	);
+
+	ALTER TABLE packs DROP COLUMN firstLabel;
+	ALTER TABLE packs DROP COLUMN secondLabel;
+`
+
+// addSQLiteColumn adds a column unless it is already there, which is the case
+// for databases created before migrations were tracked.
+func addSQLiteColumn(table string, column string, definition string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&count); err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return nil
+		}
+
+		_, err := tx.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+		return err
+	}
+}
+
+// CreatePack adds the pack and its options together, filling in their ids.
+func (r *SQLiteRepository) CreatePack(pack quiz.Pack) (*quiz.Pack, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("INSERT INTO packs(slug, name) values(?,?)", pack.Slug, pack.Name)
+	if err != nil {
+		if isSQLiteUniqueErr(err) {
+			return nil, ErrDuplicate
+		}
+		return nil, err
+	}
+
+	if pack.Id, err = res.LastInsertId(); err != nil {
+		return nil, err
+	}
+
+	pack.Options = append([]quiz.AnswerOption(nil), pack.Options...)
+	for i := range pack.Options {
+		res, err := tx.Exec("INSERT INTO answerOptions(packId, position, label) values(?,?,?)", pack.Id, i, pack.Options[i].Label)
+		if err != nil {
+			if isSQLiteUniqueErr(err) {
+				return nil, ErrDuplicate
+			}
+			return nil, err
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		pack.Options[i].Id = quiz.Answer(id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &pack, nil
+}
+
+func (r *SQLiteRepository) GetPackById(id int64) (*quiz.Pack, error) {
+	return r.getPack(r.db.QueryRow("SELECT "+packColumns+" FROM packs WHERE id = ?", id))
+}
+
+func (r *SQLiteRepository) GetPackBySlug(slug string) (*quiz.Pack, error) {
+	return r.getPack(r.db.QueryRow("SELECT "+packColumns+" FROM packs WHERE slug = ?", slug))
+}
+
+func (r *SQLiteRepository) getPack(row *sql.Row) (*quiz.Pack, error) {
+	pack, err := scanPack(row)
+	if err != nil {
+		return nil, err
+	}
+
+	packs := []quiz.Pack{*pack}
+	if err := loadOptions(r.db, packs); err != nil {
+		return nil, err
+	}
+	return &packs[0], nil
+}
+
+func (r *SQLiteRepository) AllPacks() ([]quiz.Pack, error) {
+	rows, err := r.db.Query("SELECT " + packColumns + " FROM packs ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	packs, err := scanPacks(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return packs, loadOptions(r.db, packs)
+}
+
+func (r *SQLiteRepository) UpdatePack(pack quiz.Pack) (*quiz.Pack, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE packs SET name = ? WHERE id = ?", pack.Name, pack.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrNotExists
+	}
+
+	var position int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(position), -1) + 1 FROM answerOptions WHERE packId = ?", pack.Id).Scan(&position); err != nil {
+		return nil, err
+	}
+
+	for _, option := range pack.Options {
+		if option.Id != 0 {
+			continue
+		}
+
+		if _, err := tx.Exec("INSERT INTO answerOptions(packId, position, label) values(?,?,?)", pack.Id, position, option.Label); err != nil {
+			if isSQLiteUniqueErr(err) {
+				return nil, ErrDuplicate
+			}
+			return nil, err
+		}
+		position++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.GetPackById(pack.Id)
+}
+
 func (r *SQLiteRepository) AddGameQuestion(gameId uuid.UUID, questionId int64) error {
 	_, err := r.db.Exec("INSERT INTO gameQuestions(id, gameId, questionId) values(NULL,?,?)", gameId, questionId)
 
 	return err
 }
 
+func (r *SQLiteRepository) ClaimCurrentQuestion(gameId uuid.UUID, questionId int64) error {
+	res, err := r.db.Exec(
+		"UPDATE games SET currentQuestionId = 0 WHERE id = ? AND currentQuestionId = ? AND currentQuestionId != 0",
+		gameId,
+		questionId)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUpdateFailed
+	}
+
+	return nil
+}
+
 func (r *SQLiteRepository) RemoveGameQuestions(gameId uuid.UUID) error {
 	_, err := r.db.Exec("DELETE FROM gameQuestions WHERE gameId = ?", gameId)
 
@@ -71,34 +418,45 @@ func (r *SQLiteRepository) RemoveGameQuestions(gameId uuid.UUID) error {
 
 func (r *SQLiteRepository) GetUnansweredQuestions(gameId uuid.UUID) ([]quiz.Question, error) {
 	rows, err := r.db.Query(`--sql
-		SELECT id, question, answer
-		FROM questions
-		WHERE id NOT IN (
+		SELECT `+snapshotQuestionColumns+`
+		FROM questions q
+		LEFT JOIN gameQuestionStats s ON s.gameId = ?1 AND s.questionId = q.id
+		WHERE q.id NOT IN (
 			SELECT questionId
 			FROM gameQuestions
-			WHERE gameId = ?
-		)`,
+			WHERE gameId = ?1
+		) AND q.disabled = 0
+		AND q.packId = (SELECT packId FROM games WHERE id = ?1)
+		ORDER BY q.id`,
 		gameId)
 	if err != nil {
 		return nil, err
 	}
 
-	defer rows.Close()
-
-	var all []quiz.Question
-	for rows.Next() {
-		var question quiz.Question
-		if err := rows.Scan(&question.Id, &question.Question, &question.Answer); err != nil {
-			return nil, err
-		}
-		all = append(all, question)
-	}
-	return all, nil
+	return scanQuestions(rows)
 }
 
 func (r *SQLiteRepository) CreateQuestion(question quiz.Question) (*quiz.Question, error) {
-	res, err := r.db.Exec("INSERT INTO questions(question, answer) values(?,?)", question.Question, question.Answer)
+	return createSQLiteQuestion(r.db, question)
+}
+
+func createSQLiteQuestion(db execer, question quiz.Question) (*quiz.Question, error) {
+	if question.PackId == 0 {
+		question.PackId = quiz.DefaultPackId
+	}
+
+	res, err := db.Exec(
+		"INSERT INTO questions(question, answer, source, notes, disabled, packId) values(?,?,?,?,?,?)",
+		question.Question,
+		question.Answer,
+		question.Source,
+		question.Notes,
+		question.Disabled,
+		question.PackId)
 	if err != nil {
+		if isSQLiteUniqueErr(err) {
+			return nil, ErrDuplicate
+		}
 		return nil, err
 	}
 
@@ -112,81 +470,429 @@ func (r *SQLiteRepository) CreateQuestion(question quiz.Question) (*quiz.Questio
 }
 
 func (r *SQLiteRepository) GetQuestionById(id int64) (*quiz.Question, error) {
-	row := r.db.QueryRow("SELECT * FROM questions WHERE id = ?", id)
+	row := r.db.QueryRow("SELECT "+questionColumns+" FROM questions WHERE id = ?", id)
+
+	return scanQuestion(row)
+}
 
-	var question quiz.Question
-	if err := row.Scan(&question.Id, &question.Question, &question.Answer); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNotExists
+func (r *SQLiteRepository) GetQuestionByText(text string) (*quiz.Question, error) {
+	row := r.db.QueryRow("SELECT "+questionColumns+" FROM questions WHERE question = ?", text)
+
+	return scanQuestion(row)
+}
+
+func (r *SQLiteRepository) UpdateQuestion(question quiz.Question) (*quiz.Question, error) {
+	return updateSQLiteQuestion(r.db, question)
+}
+
+func updateSQLiteQuestion(db execer, question quiz.Question) (*quiz.Question, error) {
+	res, err := db.Exec(
+		"UPDATE questions SET question = ?, answer = ?, source = ?, notes = ?, disabled = ?, packId = ? WHERE id = ?",
+		question.Question,
+		question.Answer,
+		question.Source,
+		question.Notes,
+		question.Disabled,
+		question.PackId,
+		question.Id)
+
+	if err != nil {
+		if isSQLiteUniqueErr(err) {
+			return nil, ErrDuplicate
 		}
 		return nil, err
 	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrUpdateFailed
+	}
+
 	return &question, nil
 }
 
-func (r *SQLiteRepository) CountQuestions() int64 {
-	row := r.db.QueryRow("SELECT COUNT(*) FROM Products;")
+func (r *SQLiteRepository) AllQuestions() ([]quiz.Question, error) {
+	rows, err := r.db.Query("SELECT " + questionColumns + " FROM questions ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
 
-	var count int64
-	row.Scan(&count)
-	return count
+	return scanQuestions(rows)
 }
 
-func (r *SQLiteRepository) CreateGame(playerName string) (*quiz.Game, error) {
-	newUuid, _ := uuid.NewUUID()
+func (r *SQLiteRepository) ListQuestions(search string, offset int, limit int) ([]quiz.Question, int64, error) {
+	pattern := "%" + search + "%"
 
-	game := quiz.Game{Id: newUuid, PlayerName: playerName, QuestionsAnswered: 0, Score: 0, InProgress: true, Created: time.Now()}
+	var total int64
+	row := r.db.QueryRow("SELECT COUNT(*) FROM questions WHERE question LIKE ?1 OR source LIKE ?1 OR notes LIKE ?1", pattern)
+	if err := row.Scan(&total); err != nil {
+		return nil, 0, err
+	}
 
-	uuidBytes := game.Id
-	_, err := r.db.Exec(
-		"INSERT INTO games(id, playerName, questionsAnswered, score, inProgress, created) values(?,?,?,?,?,?)",
-		uuidBytes,
-		game.PlayerName,
-		game.QuestionsAnswered,
-		game.Score,
-		game.InProgress,
-		game.Created)
+	rows, err := r.db.Query(`--sql
+		SELECT `+questionColumns+`
+		FROM questions
+		WHERE question LIKE ?1 OR source LIKE ?1 OR notes LIKE ?1
+		ORDER BY id
+		LIMIT ?2 OFFSET ?3`,
+		pattern, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	questions, err := scanQuestions(rows)
+	return questions, total, err
+}
+
+func (r *SQLiteRepository) DeleteQuestion(id int64) error {
+	return deleteSQLiteQuestion(r.db, id)
+}
+
+func deleteSQLiteQuestion(db execer, id int64) error {
+	res, err := db.Exec("DELETE FROM questions WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrDeleteFailed
+	}
+
+	return nil
+}
+
+func (r *SQLiteRepository) RecordAnswer(questionId int64, correct bool) error {
+	res, err := r.db.Exec(
+		"UPDATE questions SET timesServed = timesServed + 1, timesCorrect = timesCorrect + ? WHERE id = ?",
+		boolToInt(correct), questionId)
+	if err != nil {
+		return err
+	}
 
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUpdateFailed
+	}
+
+	return nil
+}
+
+func (r *SQLiteRepository) AddAuditEntry(entry quiz.AuditEntry) error {
+	return addSQLiteAuditEntry(r.db, entry)
+}
+
+func addSQLiteAuditEntry(db execer, entry quiz.AuditEntry) error {
+	_, err := db.Exec(
+		"INSERT INTO questionAudit(actor, action, questionId, question, detail, at) values(?,?,?,?,?,?)",
+		entry.Actor,
+		entry.Action,
+		entry.QuestionId,
+		entry.Question,
+		entry.Detail,
+		entry.At.UTC())
+
+	return err
+}
+
+func (r *SQLiteRepository) CreateQuestionAudited(question quiz.Question, entry quiz.AuditEntry) (*quiz.Question, error) {
+	var created *quiz.Question
+	err := inTransaction(r.db, func(tx *sql.Tx) error {
+		var err error
+		if created, err = createSQLiteQuestion(tx, question); err != nil {
+			return err
+		}
+		return addSQLiteAuditEntry(tx, entry.For(*created))
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &game, nil
+	return created, nil
 }
 
-func (r *SQLiteRepository) GetGameById(id uuid.UUID) (*quiz.Game, error) {
-	row := r.db.QueryRow("SELECT playerName, questionsAnswered, score, inProgress, created, completed FROM games WHERE id = ?", id)
+func (r *SQLiteRepository) UpdateQuestionAudited(question quiz.Question, entry quiz.AuditEntry) (*quiz.Question, error) {
+	var updated *quiz.Question
+	err := inTransaction(r.db, func(tx *sql.Tx) error {
+		var err error
+		if updated, err = updateSQLiteQuestion(tx, question); err != nil {
+			return err
+		}
+		return addSQLiteAuditEntry(tx, entry.For(*updated))
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	var createdStr *string
-	var completedStr *string
+	return updated, nil
+}
+
+func (r *SQLiteRepository) DeleteQuestionAudited(question quiz.Question, entry quiz.AuditEntry) error {
+	return inTransaction(r.db, func(tx *sql.Tx) error {
+		if err := deleteSQLiteQuestion(tx, question.Id); err != nil {
+			return err
+		}
+		return addSQLiteAuditEntry(tx, entry.For(question))
+	})
+}
+
+func (r *SQLiteRepository) AuditEntries(limit int) ([]quiz.AuditEntry, error) {
+	rows, err := r.db.Query("SELECT id, actor, action, questionId, question, detail, at FROM questionAudit ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAuditEntries(rows)
+}
+
+func (r *SQLiteRepository) CountQuestions() (int64, error) {
+	row := r.db.QueryRow("SELECT COUNT(*) FROM questions")
+
+	var count int64
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *SQLiteRepository) CountEnabledQuestions(packId int64) (int64, error) {
+	row := r.db.QueryRow("SELECT COUNT(*) FROM questions WHERE disabled = 0 AND packId = ?", packId)
+
+	var count int64
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *SQLiteRepository) CreatePlayer(username string, passwordHash string) (*quiz.Player, error) {
+	player := quiz.Player{Username: username, PasswordHash: passwordHash, Created: time.Now().UTC()}
 
-	var game = quiz.Game{Id: id}
-	if err := row.Scan(&game.PlayerName, &game.QuestionsAnswered, &game.Score, &game.InProgress, &createdStr, &completedStr); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNotExists
+	res, err := r.db.Exec("INSERT INTO players(username, passwordHash, created) values(?,?,?)", player.Username, player.PasswordHash, player.Created)
+	if err != nil {
+		if isSQLiteUniqueErr(err) {
+			return nil, ErrDuplicate
 		}
 		return nil, err
 	}
 
-	if createdStr != nil {
-		game.Created, _ = time.Parse("2006-01-02 15:04:05+00:00", *createdStr)
+	if player.Id, err = res.LastInsertId(); err != nil {
+		return nil, err
+	}
+
+	return &player, nil
+}
+
+func (r *SQLiteRepository) GetPlayerById(id int64) (*quiz.Player, error) {
+	row := r.db.QueryRow("SELECT "+playerColumns+" FROM players WHERE id = ?", id)
+
+	return scanPlayer(row)
+}
+
+func (r *SQLiteRepository) GetPlayerByUsername(username string) (*quiz.Player, error) {
+	row := r.db.QueryRow("SELECT "+playerColumns+" FROM players WHERE lower(username) = lower(?)", username)
+
+	return scanPlayer(row)
+}
+
+func (r *SQLiteRepository) PlayerGames(playerId int64) ([]quiz.Game, error) {
+	rows, err := r.db.Query("SELECT "+gameColumns+" FROM games WHERE playerId = ? ORDER BY created DESC", playerId)
+	if err != nil {
+		return nil, err
 	}
-	if completedStr != nil {
-		game.Completed, _ = time.Parse("2006-01-02 15:04:05+00:00", *completedStr)
+
+	return scanGames(rows)
+}
+
+func (r *SQLiteRepository) CreateSession(playerId int64, expires time.Time) (*quiz.Session, error) {
+	session := quiz.Session{Token: quiz.NewSessionToken(), PlayerId: playerId, Created: time.Now().UTC(), Expires: expires.UTC()}
+
+	_, err := r.db.Exec("INSERT INTO sessions(token, playerId, created, expires) values(?,?,?,?)", session.Token, session.PlayerId, session.Created, session.Expires)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (r *SQLiteRepository) GetSession(token string) (*quiz.Session, error) {
+	row := r.db.QueryRow("SELECT "+sessionColumns+" FROM sessions WHERE token = ?", token)
+
+	return scanSession(row)
+}
+
+func (r *SQLiteRepository) DeleteSession(token string) error {
+	res, err := r.db.Exec("DELETE FROM sessions WHERE token = ?", token)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrDeleteFailed
+	}
+
+	return nil
+}
+
+func (r *SQLiteRepository) CreateGameSession(expires time.Time) (*quiz.GameSession, error) {
+	session := quiz.GameSession{Token: quiz.NewSessionToken(), Created: time.Now().UTC(), Expires: expires.UTC()}
+
+	res, err := r.db.Exec("INSERT INTO gameSessions(token, created, expires) values(?,?,?)", session.Token, session.Created, session.Expires)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Id, err = res.LastInsertId(); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (r *SQLiteRepository) GetGameSession(token string) (*quiz.GameSession, error) {
+	row := r.db.QueryRow("SELECT "+gameSessionColumns+" FROM gameSessions WHERE token = ?", token)
+
+	return scanGameSession(row)
+}
+
+func (r *SQLiteRepository) RotateGameSession(id int64, expires time.Time) (*quiz.GameSession, error) {
+	row := r.db.QueryRow(
+		"UPDATE gameSessions SET token = ?, expires = ? WHERE id = ? RETURNING "+gameSessionColumns,
+		quiz.NewSessionToken(), expires.UTC(), id)
+
+	return scanGameSession(row)
+}
+
+func (r *SQLiteRepository) DeleteGameSession(token string) error {
+	res, err := r.db.Exec("DELETE FROM gameSessions WHERE token = ?", token)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrDeleteFailed
+	}
+
+	return nil
+}
+
+func (r *SQLiteRepository) GetSessionGame(sessionId int64, gameId uuid.UUID) (*quiz.Game, error) {
+	if gameId == uuid.Nil {
+		row := r.db.QueryRow("SELECT "+gameColumns+" FROM games WHERE sessionId = ? ORDER BY created DESC LIMIT 1", sessionId)
+		return scanGame(row)
+	}
+
+	row := r.db.QueryRow("SELECT "+gameColumns+" FROM games WHERE sessionId = ? AND id = ?", sessionId, gameId)
+	return scanGame(row)
+}
+
+func (r *SQLiteRepository) CreateGame(start quiz.NewGame) (*quiz.Game, error) {
+	return r.insertGame(newGame(start, time.Now().UTC()))
+}
+
+// insertGame adds the game along with a snapshot of its pack's question
+// statistics, which its questions are picked with.
+func (r *SQLiteRepository) insertGame(game quiz.Game) (*quiz.Game, error) {
+	err := inTransaction(r.db, func(tx *sql.Tx) error {
+		if game.RoomCode != "" {
+			if err := checkRoomLobby(tx, "SELECT state FROM rooms WHERE code = ?", game.RoomCode); err != nil {
+				return err
+			}
+		}
+
+		if err := insertSQLiteGame(tx, game); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`--sql
+			INSERT INTO gameQuestionStats(gameId, questionId, timesServed, timesCorrect)
+			SELECT ?, id, timesServed, timesCorrect
+			FROM questions
+			WHERE packId = ? AND timesServed > 0`,
+			game.Id,
+			game.PackId)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &game, nil
 }
 
+func insertSQLiteGame(tx *sql.Tx, game quiz.Game) error {
+	_, err := tx.Exec(
+		"INSERT INTO games(id, playerName, questionsAnswered, score, inProgress, created, mode, packId, seed, challengeDay, roomCode, playerId, sessionId) values(?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		game.Id,
+		game.PlayerName,
+		game.QuestionsAnswered,
+		game.Score,
+		game.InProgress,
+		game.Created,
+		game.Mode,
+		game.PackId,
+		game.Seed,
+		game.ChallengeDay,
+		game.RoomCode,
+		game.PlayerId,
+		game.SessionId)
+
+	if isSQLiteUniqueErr(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *SQLiteRepository) GetGameById(id uuid.UUID) (*quiz.Game, error) {
+	row := r.db.QueryRow("SELECT "+gameColumns+" FROM games WHERE id = ?", id)
+
+	return scanGame(row)
+}
+
 func (r *SQLiteRepository) UpdateGame(game *quiz.Game) (*quiz.Game, error) {
 	res, err := r.db.Exec(
-		"UPDATE games SET playerName = ?, questionsAnswered = ?, score = ?, inProgress = ?, created = ?, completed = ? WHERE id = ?",
+		"UPDATE games SET playerName = ?, questionsAnswered = ?, score = ?, inProgress = ?, created = ?, completed = ?, currentQuestionId = ?, mode = ?, mistakes = ?, questionServed = ?, streak = ?, bestStreak = ?, outOfQuestions = ?, packId = ?, seed = ?, challengeDay = ?, roomCode = ?, playerId = ?, sessionId = ? WHERE id = ?",
 		game.PlayerName,
 		game.QuestionsAnswered,
 		game.Score,
 		game.InProgress,
-		game.Created,
-		game.Completed,
+		game.Created.UTC(),
+		game.Completed.UTC(),
+		game.CurrentQuestionId,
+		game.Mode,
+		game.Mistakes,
+		game.QuestionServed.UTC(),
+		game.Streak,
+		game.BestStreak,
+		game.OutOfQuestions,
+		game.PackId,
+		game.Seed,
+		game.ChallengeDay,
+		game.RoomCode,
+		game.PlayerId,
+		game.SessionId,
 		game.Id)
 
 	if err != nil {
@@ -206,43 +912,129 @@ func (r *SQLiteRepository) UpdateGame(game *quiz.Game) (*quiz.Game, error) {
 }
 
 func (r *SQLiteRepository) AllGames() ([]quiz.Game, error) {
-	rows, err := r.db.Query("SELECT id, playerName, questionsAnswered, score, inProgress FROM games")
+	rows, err := r.db.Query("SELECT " + gameColumns + " FROM games ORDER BY created")
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var all []quiz.Game
-	for rows.Next() {
-		var game quiz.Game
-		if err := rows.Scan(&game.Id, &game.PlayerName, &game.QuestionsAnswered, &game.Score, &game.InProgress); err != nil {
-			return nil, err
+	return scanGames(rows)
+}
+
+func (r *SQLiteRepository) CreateRoom(code string, packId int64, hostGameId uuid.UUID) (*quiz.Room, error) {
+	room := quiz.Room{Code: code, PackId: packId, HostGameId: hostGameId, State: quiz.RoomLobby, Seed: rand.Int63(), Created: time.Now().UTC()}
+
+	_, err := r.db.Exec(
+		"INSERT INTO rooms(code, packId, hostGameId, state, seed, created) values(?,?,?,?,?,?)",
+		room.Code,
+		room.PackId,
+		room.HostGameId,
+		room.State,
+		room.Seed,
+		room.Created)
+
+	if err != nil {
+		if isSQLiteUniqueErr(err) || isSQLitePrimaryKeyErr(err) {
+			return nil, ErrDuplicate
 		}
-		all = append(all, game)
+		return nil, err
+	}
+
+	return &room, nil
+}
+
+func (r *SQLiteRepository) GetRoomByCode(code string) (*quiz.Room, error) {
+	row := r.db.QueryRow("SELECT "+roomColumns+" FROM rooms WHERE code = ?", code)
+
+	return scanRoom(row)
+}
+
+func (r *SQLiteRepository) UpdateRoom(room *quiz.Room, previous quiz.Room) (*quiz.Room, error) {
+	res, err := r.db.Exec(
+		"UPDATE rooms SET state = ?, currentQuestionId = ?, questionsAsked = ?, questionServed = ? WHERE code = ? AND state = ? AND questionsAsked = ?",
+		room.State,
+		room.CurrentQuestionId,
+		room.QuestionsAsked,
+		room.QuestionServed,
+		room.Code,
+		previous.State,
+		previous.QuestionsAsked)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrUpdateFailed
+	}
+
+	return room, nil
+}
+
+func (r *SQLiteRepository) RoomGames(code string) ([]quiz.Game, error) {
+	rows, err := r.db.Query("SELECT "+gameColumns+" FROM games WHERE roomCode = ? ORDER BY created", code)
+	if err != nil {
+		return nil, err
 	}
-	return all, nil
+
+	return scanGames(rows)
 }
 
-func (r *SQLiteRepository) TopTenCompletedGames(sinceTime string) ([]quiz.Game, error) {
+func (r *SQLiteRepository) TopTenCompletedGames(mode string, since time.Time) ([]quiz.Game, error) {
 	rows, err := r.db.Query(`--sql
-	SELECT id, playerName, questionsAnswered, score, inProgress 
+	SELECT `+gameColumns+`
 	FROM games 
-	WHERE inProgress=0 AND completed > date('now', ?)
+	WHERE inProgress=0 AND mode = ? AND completed > ?
 	ORDER BY score 
 	DESC LIMIT 10
-	`, sinceTime)
+	`, mode, since.UTC())
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var all []quiz.Game
-	for rows.Next() {
-		var game quiz.Game
-		if err := rows.Scan(&game.Id, &game.PlayerName, &game.QuestionsAnswered, &game.Score, &game.InProgress); err != nil {
-			return nil, err
-		}
-		all = append(all, game)
+	return scanGames(rows)
+}
+
+func (r *SQLiteRepository) TopTenStreaks(mode string, since time.Time) ([]quiz.Game, error) {
+	rows, err := r.db.Query(`--sql
+	SELECT `+gameColumns+`
+	FROM games
+	WHERE inProgress=0 AND mode = ? AND completed > ?
+	ORDER BY bestStreak
+	DESC LIMIT 10
+	`, mode, since.UTC())
+	if err != nil {
+		return nil, err
 	}
-	return all, nil
+
+	return scanGames(rows)
+}
+
+func (r *SQLiteRepository) TopTenDailyGames(day string) ([]quiz.Game, error) {
+	rows, err := r.db.Query(`--sql
+	SELECT `+gameColumns+`
+	FROM games
+	WHERE inProgress=0 AND challengeDay = ?
+	ORDER BY score DESC, completed
+	LIMIT 10
+	`, day)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanGames(rows)
+}
+
+func isSQLiteUniqueErr(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+func isSQLitePrimaryKeyErr(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
 }
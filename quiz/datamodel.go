@@ -6,35 +6,233 @@ import (
 	"github.com/google/uuid"
 )
 
+// Answer is the id of one of a pack's answer options.
 type Answer int64
 
+// Fintech and Furniture are the options of the default pack, which the
+// migrations create with these ids.
 const (
-	Fintech   Answer = 0
-	Furniture Answer = 1
+	Fintech   Answer = 1
+	Furniture Answer = 2
 )
 
 type Question struct {
-	Id       int64
-	Question string
-	Answer   Answer
+	Id       int64  `json:"id"`
+	Question string `json:"question"`
+	Answer   Answer `json:"-"`
+	Source   string `json:"-"`
+	Notes    string `json:"-"`
+	Disabled bool   `json:"-"`
+	PackId   int64  `json:"packId"`
+	// TimesServed and TimesCorrect count the answers given in time to this
+	// question, which its Difficulty is derived from.
+	TimesServed  int64 `json:"-"`
+	TimesCorrect int64 `json:"-"`
+}
+
+// AuditEntry records a change made to the question bank from the admin
+// console.
+type AuditEntry struct {
+	Id         int64
+	Actor      string
+	Action     string
+	QuestionId int64
+	Question   string
+	Detail     string
+	At         time.Time
+}
+
+// For returns the entry about question, whose id and word it records.
+func (entry AuditEntry) For(question Question) AuditEntry {
+	entry.QuestionId = question.Id
+	entry.Question = question.Question
+	return entry
 }
 
 type Game struct {
-	Id                uuid.UUID
-	PlayerName        string
-	QuestionsAnswered int64
-	Score             int64
-	InProgress        bool
-	Created           time.Time
-	Completed         time.Time
+	Id                uuid.UUID `json:"id"`
+	PlayerName        string    `json:"playerName"`
+	QuestionsAnswered int64     `json:"questionsAnswered"`
+	Score             int64     `json:"score"`
+	InProgress        bool      `json:"inProgress"`
+	Created           time.Time `json:"created"`
+	Completed         time.Time `json:"completed"`
+	CurrentQuestionId int64     `json:"currentQuestionId"`
+	Mode              string    `json:"mode"`
+	Mistakes          int64     `json:"mistakes"`
+	// QuestionServed is when the current question was first served, which
+	// per-question time limits are measured from.
+	QuestionServed time.Time `json:"questionServed"`
+	// Streak is the current run of correct answers and BestStreak the
+	// longest so far.
+	Streak     int64 `json:"streak"`
+	BestStreak int64 `json:"bestStreak"`
+	// OutOfQuestions is set when the game ended because every question in
+	// the bank had been asked.
+	OutOfQuestions bool  `json:"outOfQuestions"`
+	PackId         int64 `json:"packId"`
+	// Seed is where the game's choice of questions starts from, so the same
+	// seed asks the same questions given the same bank and answers.
+	Seed int64 `json:"seed"`
+	// ChallengeDay is the day of the daily challenge the game was played
+	// for, and empty for other modes.
+	ChallengeDay string `json:"challengeDay,omitempty"`
+	// RoomCode is the room the game is played in, and empty for games
+	// played alone.
+	RoomCode string `json:"roomCode,omitempty"`
+	// PlayerId is the registered player who played the game, and 0 for
+	// guests.
+	PlayerId int64 `json:"playerId,omitempty"`
+	// SessionId is the game session that owns the game, and is never shown
+	// since its games are only reached with the session's token.
+	SessionId int64 `json:"-"`
+}
+
+// NewGame is what a game is started with. The rest of the game starts out
+// empty.
+type NewGame struct {
+	PlayerName string
+	Mode       string
+	PackId     int64
+	// ChallengeDay is set for a game of a day's daily challenge, whose seed
+	// it decides.
+	ChallengeDay string
+	// RoomCode is set for a game joining a room, which must still be in the
+	// lobby.
+	RoomCode  string
+	PlayerId  int64
+	SessionId int64
+}
+
+// LivesLeft is only meaningful when the game's mode has lives.
+func (game Game) LivesLeft(mode GameMode) int64 {
+	return mode.Lives - game.Mistakes
+}
+
+// ScoreOutOf is the best score the questions answered so far could have
+// earned in mode.
+func (game Game) ScoreOutOf(mode GameMode) int64 {
+	return game.QuestionsAnswered * mode.PointsPerQuestion()
+}
+
+// QuestionTimeLimit is how long the player has to answer each question, or
+// 0 for no limit. Games in a room have RoomQuestionTimeLimit whatever their
+// mode.
+func (game Game) QuestionTimeLimit(mode GameMode) time.Duration {
+	if game.RoomCode != "" {
+		return RoomQuestionTimeLimit
+	}
+	return mode.QuestionTimeLimit
+}
+
+// QuestionTimeLeft is how long remains to answer the current question, or 0
+// when there is no per-question time limit.
+func (game Game) QuestionTimeLeft(mode GameMode, now time.Time) time.Duration {
+	limit := game.QuestionTimeLimit(mode)
+	if limit == 0 {
+		return 0
+	}
+
+	left := game.QuestionServed.Add(limit).Sub(now)
+	if left < 0 {
+		return 0
+	}
+	return left
 }
 
 type QuestionPageStruct struct {
-	Question Question
-	Game     Game
+	Question Question `json:"question"`
+	Game     Game     `json:"game"`
+	// Mode is the game's mode as the server offers it.
+	Mode GameMode `json:"-"`
+	Pack Pack     `json:"pack"`
+	// SecondsLeft is how long the player has to answer, when the mode has a
+	// per-question time limit.
+	SecondsLeft int64 `json:"secondsLeft,omitempty"`
 }
 
 type NextQuestionModalStruct struct {
-	Correct bool
-	Score   int64
+	Correct bool  `json:"correct"`
+	Score   int64 `json:"score"`
+	// OutOfTime is set when the answer arrived after the game's or the
+	// question's time limit and so was not scored.
+	OutOfTime bool `json:"outOfTime"`
+	// Points is what the answer scored.
+	Points int64 `json:"points"`
+	// RoomCode is set for games in a room, and WaitingForRoom when other
+	// players have still to answer before the room moves on.
+	RoomCode       string `json:"roomCode,omitempty"`
+	WaitingForRoom bool   `json:"waitingForRoom,omitempty"`
+}
+
+// RoomPageStruct shows a room to one of its players, or to everyone in it
+// when Game is empty.
+type RoomPageStruct struct {
+	Room    Room
+	Game    Game
+	Players []Game
+}
+
+// IsHost reports whether the page is being shown to the room's host.
+func (page RoomPageStruct) IsHost() bool {
+	return page.Game.Id == page.Room.HostGameId
+}
+
+// IsHostGame reports whether game is the host's.
+func (page RoomPageStruct) IsHostGame(game Game) bool {
+	return game.Id == page.Room.HostGameId
+}
+
+type IndexPageStruct struct {
+	Modes Modes
+	Packs []Pack
+	// Player is the logged in player, if any.
+	Player *Player
+}
+
+// EndPageStruct shows a finished game's score.
+type EndPageStruct struct {
+	Game Game
+	Mode GameMode
+}
+
+// ProfilePageStruct shows a player's best games and recent history, with
+// the modes they were played in.
+type ProfilePageStruct struct {
+	Player Player
+	Bests  []Game
+	Recent []Game
+	Modes  Modes
+}
+
+type LeaderboardStruct struct {
+	Games      []Game
+	Mode       GameMode
+	Modes      Modes
+	TimeSelect string
+	// Day is set on the daily challenge leaderboard.
+	Day string
+}
+
+type AdminQuestionTableStruct struct {
+	Rows []AdminQuestionRowStruct
+	// Packs are offered when adding a question.
+	Packs  []Pack
+	Search string
+	Total  int64
+	Page   int
+	Pages  int
+	// PreviousPage and NextPage are 0 when there is no such page.
+	PreviousPage int
+	NextPage     int
+	Error        string
+}
+
+type AdminQuestionRowStruct struct {
+	Question Question
+	// Pack is the one the question is in and Packs every pack it could be
+	// moved to.
+	Pack  Pack
+	Packs []Pack
+	Error string
 }
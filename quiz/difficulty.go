@@ -0,0 +1,33 @@
+package quiz
+
+import "math"
+
+const (
+	// StartingDifficulty is what a game's first question aims for, and
+	// DifficultyStep how much harder each correct answer makes the next.
+	StartingDifficulty = 0.25
+	DifficultyStep     = 0.05
+	MaxDifficulty      = 0.9
+
+	// SelectionPool is how many of the questions closest to the target
+	// difficulty DifficultySelector picks from, so games still vary.
+	SelectionPool = 3
+)
+
+// Difficulty rates how often the question is answered wrongly, from 0 for
+// always right to 1 for always wrong. It starts at 0.5 and moves toward the
+// observed rate as answers come in, so a question answered once is not
+// rated as trivial or impossible.
+func (question Question) Difficulty() float64 {
+	wrong := question.TimesServed - question.TimesCorrect
+
+	return (float64(wrong) + 1) / (float64(question.TimesServed) + 2)
+}
+
+// TargetDifficulty is how hard the game's next question should be. It ramps
+// up with every correct answer.
+func TargetDifficulty(game Game) float64 {
+	correct := game.QuestionsAnswered - game.Mistakes
+
+	return math.Min(StartingDifficulty+DifficultyStep*float64(correct), MaxDifficulty)
+}
@@ -0,0 +1,37 @@
+package quiz
+
+import "testing"
+
+func TestQuestion_Difficulty(t *testing.T) {
+	unanswered := Question{}
+	if unanswered.Difficulty() != 0.5 {
+		t.Fatal(unanswered.Difficulty())
+	}
+
+	easy := Question{TimesServed: 98, TimesCorrect: 98}
+	hard := Question{TimesServed: 98, TimesCorrect: 0}
+	if easy.Difficulty() != 0.01 || hard.Difficulty() != 0.99 {
+		t.Fatal(easy.Difficulty(), hard.Difficulty())
+	}
+
+	once := Question{TimesServed: 1, TimesCorrect: 1}
+	if d := once.Difficulty(); d <= 0.25 || d >= 0.5 {
+		t.Fatal(d)
+	}
+}
+
+func TestTargetDifficulty(t *testing.T) {
+	if target := TargetDifficulty(Game{}); target != StartingDifficulty {
+		t.Fatal(target)
+	}
+
+	scored := TargetDifficulty(Game{QuestionsAnswered: 6, Mistakes: 2})
+	unscored := TargetDifficulty(Game{QuestionsAnswered: 6, Mistakes: 6})
+	if scored <= unscored {
+		t.Fatal(scored, unscored)
+	}
+
+	if target := TargetDifficulty(Game{QuestionsAnswered: 100}); target != MaxDifficulty {
+		t.Fatal(target)
+	}
+}
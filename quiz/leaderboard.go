@@ -0,0 +1,24 @@
+package quiz
+
+import (
+	"errors"
+	"time"
+)
+
+// LeaderboardSince converts a leaderboard time-select value into the time
+// from which completed games should be included. An empty selection means
+// today.
+func LeaderboardSince(selection string, now time.Time) (time.Time, error) {
+	now = now.UTC()
+
+	switch selection {
+	case "", "start of day":
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	case "start of month":
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	case "-1000 years":
+		return now.AddDate(-1000, 0, 0), nil
+	}
+
+	return time.Time{}, errors.New("time-select should be 'start of day', 'start of month' or '-1000 years'")
+}
@@ -0,0 +1,33 @@
+package quiz
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaderboardSince(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 13, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		selection string
+		expected  time.Time
+	}{
+		{"", time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		{"start of day", time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		{"start of month", time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{"-1000 years", time.Date(1024, time.March, 15, 13, 30, 0, 0, time.UTC)},
+	}
+
+	for _, v := range tests {
+		since, err := LeaderboardSince(v.selection, now)
+		if err != nil || !since.Equal(v.expected) {
+			t.Fatal(v.selection, since, err)
+		}
+	}
+}
+
+func TestLeaderboardSince_Invalid(t *testing.T) {
+	if _, err := LeaderboardSince("yesterday", time.Now()); err == nil {
+		t.Fatal("expected error")
+	}
+}
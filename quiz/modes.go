@@ -0,0 +1,163 @@
+package quiz
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+type ScoringRule string
+
+const (
+	// ScoreOnePerCorrect scores a point for each correct answer.
+	ScoreOnePerCorrect ScoringRule = "one-per-correct"
+	// ScoreSpeed scores up to SpeedPoints for a correct answer, fewer the
+	// longer the player took to give it.
+	ScoreSpeed ScoringRule = "speed"
+	// ScoreStreak ranks games by their longest run of correct answers.
+	ScoreStreak ScoringRule = "streak"
+)
+
+// SpeedPoints is the most a single answer can score under ScoreSpeed.
+const SpeedPoints = 10
+
+// GameMode sets the rules a game is played by. Zero values mean no limit.
+type GameMode struct {
+	Name  string
+	Label string
+	// Rules describes the mode beyond its length, which Description puts
+	// first for modes that have one.
+	Rules         string
+	QuestionCount int64
+	// TimeLimit is how long the player has to finish the whole game. Answers
+	// submitted after it runs out are not scored and end the game.
+	TimeLimit time.Duration
+	// QuestionTimeLimit is how long the player has to answer each question
+	// from when it is served. Answers after it count as wrong.
+	QuestionTimeLimit time.Duration
+	// Lives is how many wrong answers end the game.
+	Lives   int64
+	Scoring ScoringRule
+	// Selector, when set, picks the mode's questions in place of the one
+	// the server is configured with.
+	Selector Selector
+}
+
+const DefaultMode = "classic"
+
+// Modes is a set of game modes, such as those a server offers.
+type Modes []GameMode
+
+// GameModes are the modes as they are by default. Servers configured to
+// play longer or shorter games offer a copy from WithQuestionCounts.
+var GameModes = Modes{
+	{Name: "classic", Label: "Classic", QuestionCount: 10, Scoring: ScoreOnePerCorrect},
+	{Name: "quick", Label: "Quick", QuestionCount: 5, Scoring: ScoreOnePerCorrect},
+	{Name: "marathon", Label: "Marathon", Rules: ", three lives", QuestionCount: 20, Lives: 3, Scoring: ScoreOnePerCorrect},
+	{Name: "blitz", Label: "Blitz", Rules: " in one minute", QuestionCount: 10, TimeLimit: time.Minute, Scoring: ScoreOnePerCorrect},
+	{Name: "survival", Label: "Survival", Rules: "Keep going until your first wrong answer", Lives: 1, Scoring: ScoreStreak},
+	{Name: "timed", Label: "Timed", Rules: ", ten seconds each, faster answers score more", QuestionCount: 10, QuestionTimeLimit: 10 * time.Second, Scoring: ScoreSpeed},
+	// The daily challenge picks uniformly so that neither the player's
+	// answers nor the question statistics change which questions come up.
+	{Name: DailyMode, Label: "Daily challenge", Rules: "Today's ten Fintech or Furniture questions, the same for everyone, one go each", QuestionCount: 10, Scoring: ScoreOnePerCorrect, Selector: UniformSelector{}},
+}
+
+// Description is shown when picking a mode: how many questions it asks,
+// when that can change, followed by its Rules.
+func (mode GameMode) Description() string {
+	if mode.QuestionCount == 0 || mode.IsDaily() {
+		return mode.Rules
+	}
+	return fmt.Sprintf("%d questions%s", mode.QuestionCount, mode.Rules)
+}
+
+// PointsPerQuestion is the most a single correct answer can score.
+func (mode GameMode) PointsPerQuestion() int64 {
+	if mode.Scoring == ScoreSpeed {
+		return SpeedPoints
+	}
+	return 1
+}
+
+// IsDaily reports whether the mode is the daily challenge.
+func (mode GameMode) IsDaily() bool {
+	return mode.Name == DailyMode
+}
+
+// RanksByStreak reports whether games in the mode are ranked by their longest
+// streak rather than their score.
+func (mode GameMode) RanksByStreak() bool {
+	return mode.Scoring == ScoreStreak
+}
+
+// MaxScore is the best possible score for a full game, or 0 when the mode
+// has no fixed number of questions.
+func (mode GameMode) MaxScore() int64 {
+	return mode.QuestionCount * mode.PointsPerQuestion()
+}
+
+func (modes Modes) Get(name string) (GameMode, error) {
+	for _, mode := range modes {
+		if mode.Name == name {
+			return mode, nil
+		}
+	}
+
+	return GameMode{}, fmt.Errorf("unknown game mode %q", name)
+}
+
+// For returns the rules the game is played by, falling back to the default
+// mode for games whose mode no longer exists.
+func (modes Modes) For(game Game) GameMode {
+	mode, err := modes.Get(game.Mode)
+	if err != nil {
+		mode, _ = modes.Get(DefaultMode)
+	}
+	return mode
+}
+
+// CheckQuestionCount checks that the named mode's length can be changed to
+// count. Modes that go on until the player loses have no length to change,
+// and the daily challenge keeps its length so that days compare.
+func (modes Modes) CheckQuestionCount(name string, count int64) error {
+	mode, err := modes.Get(name)
+	if err != nil {
+		return err
+	}
+
+	if mode.QuestionCount == 0 || mode.IsDaily() {
+		return fmt.Errorf("the length of %s games cannot be changed", name)
+	}
+
+	if count < 1 {
+		return fmt.Errorf("%s games need at least one question", name)
+	}
+
+	return nil
+}
+
+// WithQuestionCounts returns a copy of the modes with the question counts
+// changed, keyed by mode name, for servers configured to play longer or
+// shorter games.
+func (modes Modes) WithQuestionCounts(counts map[string]int64) (Modes, error) {
+	changed := slices.Clone(modes)
+	for i, mode := range changed {
+		count, ok := counts[mode.Name]
+		if !ok {
+			continue
+		}
+
+		if err := changed.CheckQuestionCount(mode.Name, count); err != nil {
+			return nil, err
+		}
+		changed[i].QuestionCount = count
+	}
+
+	for name := range counts {
+		if _, err := changed.Get(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,59 @@
+package quiz
+
+import (
+	"testing"
+)
+
+func TestCheckQuestionCount(t *testing.T) {
+	for _, test := range []struct {
+		mode  string
+		count int64
+		ok    bool
+	}{
+		{"classic", 15, true},
+		{"marathon", 1, true},
+		{"classic", 0, false},
+		{"survival", 10, false},
+		{DailyMode, 20, false},
+		{"unknown", 10, false},
+	} {
+		if err := GameModes.CheckQuestionCount(test.mode, test.count); (err == nil) != test.ok {
+			t.Error(test, err)
+		}
+	}
+}
+
+func TestModes_WithQuestionCounts(t *testing.T) {
+	modes, err := GameModes.WithQuestionCounts(map[string]int64{"marathon": 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mode, _ := modes.Get("marathon")
+	if mode.QuestionCount != 30 || mode.Description() != "30 questions, three lives" || mode.MaxScore() != 30 {
+		t.Fatal(mode)
+	}
+
+	if mode, _ := GameModes.Get("marathon"); mode.QuestionCount != 20 {
+		t.Fatal("the default modes changed", mode)
+	}
+
+	for _, counts := range []map[string]int64{{"survival": 30}, {"unknown": 30}} {
+		if _, err := GameModes.WithQuestionCounts(counts); err == nil {
+			t.Fatal(counts)
+		}
+	}
+}
+
+func TestGameMode_Description(t *testing.T) {
+	for name, description := range map[string]string{
+		"classic":  "10 questions",
+		"blitz":    "10 questions in one minute",
+		"survival": "Keep going until your first wrong answer",
+		DailyMode:  "Today's ten Fintech or Furniture questions, the same for everyone, one go each",
+	} {
+		if mode, _ := GameModes.Get(name); mode.Description() != description {
+			t.Error(name, mode.Description())
+		}
+	}
+}
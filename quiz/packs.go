@@ -0,0 +1,116 @@
+package quiz
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidAnswer = errors.New("invalid answer")
+	ErrInvalidPack   = errors.New("invalid pack")
+)
+
+// DefaultPackId is the original Fintech or Furniture pack, which questions
+// and games from before packs existed belong to.
+const DefaultPackId int64 = 1
+
+var DefaultPack = Pack{
+	Id:   DefaultPackId,
+	Slug: "fintech-or-furniture",
+	Name: "Fintech or Furniture",
+	Options: []AnswerOption{
+		{Id: Fintech, Label: "Fintech"},
+		{Id: Furniture, Label: "Furniture"},
+	},
+}
+
+// Pack is a themed set of questions. Every question in a pack is answered
+// with one of the pack's options.
+type Pack struct {
+	Id      int64          `json:"id"`
+	Slug    string         `json:"slug"`
+	Name    string         `json:"name"`
+	Options []AnswerOption `json:"options"`
+}
+
+// AnswerOption is one of the answers a pack offers. Its id is what questions
+// store as their answer and what players submit.
+type AnswerOption struct {
+	Id    Answer `json:"id"`
+	Label string `json:"label"`
+}
+
+// Validate checks a pack before it is created: it needs a slug, a name and at
+// least two options with distinct labels.
+func (pack Pack) Validate() error {
+	if pack.Slug == "" || pack.Name == "" {
+		return fmt.Errorf("%w: slug and name are required", ErrInvalidPack)
+	}
+
+	if len(pack.Options) < 2 {
+		return fmt.Errorf("%w: %s needs at least two options", ErrInvalidPack, pack.Slug)
+	}
+
+	for i, option := range pack.Options {
+		if strings.TrimSpace(option.Label) == "" {
+			return fmt.Errorf("%w: %s has an option without a label", ErrInvalidPack, pack.Slug)
+		}
+
+		for _, other := range pack.Options[:i] {
+			if strings.EqualFold(option.Label, other.Label) {
+				return fmt.Errorf("%w: %s has the option %q twice", ErrInvalidPack, pack.Slug, option.Label)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Label is the text shown for an answer in this pack.
+func (pack Pack) Label(answer Answer) string {
+	for _, option := range pack.Options {
+		if option.Id == answer {
+			return option.Label
+		}
+	}
+	return "Unknown"
+}
+
+// LabelList joins the option labels for a sentence, as in "Fintech,
+// Furniture or Pharma".
+func (pack Pack) LabelList() string {
+	var labels []string
+	for _, option := range pack.Options {
+		labels = append(labels, option.Label)
+	}
+
+	if len(labels) < 2 {
+		return strings.Join(labels, "")
+	}
+	return strings.Join(labels[:len(labels)-1], ", ") + " or " + labels[len(labels)-1]
+}
+
+// CheckAnswer reports whether answer is the id of one of the pack's options.
+func (pack Pack) CheckAnswer(answer Answer) error {
+	for _, option := range pack.Options {
+		if option.Id == answer {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %d is not an option in %s", ErrInvalidAnswer, answer, pack.Name)
+}
+
+// ParseAnswer finds the answer with the given label, ignoring case. It is
+// for people typing answers in, such as in imported files; players submit
+// option ids.
+func (pack Pack) ParseAnswer(label string) (Answer, error) {
+	for _, option := range pack.Options {
+		if strings.EqualFold(strings.TrimSpace(label), option.Label) {
+			return option.Id, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: should be %s", ErrInvalidAnswer, pack.LabelList())
+}
@@ -0,0 +1,53 @@
+package quiz
+
+import (
+	"errors"
+	"testing"
+)
+
+var threeWay = Pack{Id: 3, Slug: "fintech-furniture-or-pharma", Name: "Fintech, Furniture or Pharma", Options: []AnswerOption{{7, "Fintech"}, {8, "Furniture"}, {9, "Pharma"}}}
+
+func TestPack_ParseAnswer(t *testing.T) {
+	if answer, err := threeWay.ParseAnswer(" pharma "); err != nil || answer != 9 {
+		t.Fatal(answer, err)
+	}
+
+	if _, err := threeWay.ParseAnswer("Pokémon"); !errors.Is(err, ErrInvalidAnswer) || err.Error() != "invalid answer: should be Fintech, Furniture or Pharma" {
+		t.Fatal(err)
+	}
+
+	if label := threeWay.Label(8); label != "Furniture" {
+		t.Fatal(label)
+	}
+}
+
+func TestPack_CheckAnswer(t *testing.T) {
+	if err := threeWay.CheckAnswer(9); err != nil {
+		t.Fatal(err)
+	}
+
+	// Option ids are unique across packs, so another pack's option is not
+	// an answer here even when the label matches.
+	if err := threeWay.CheckAnswer(Fintech); !errors.Is(err, ErrInvalidAnswer) {
+		t.Fatal(err)
+	}
+}
+
+func TestPack_Validate(t *testing.T) {
+	if err := threeWay.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []Pack{
+		{Slug: "one", Name: "One", Options: []AnswerOption{{Label: "Fintech"}}},
+		{Slug: "twice", Name: "Twice", Options: []AnswerOption{{Label: "Fintech"}, {Label: "fintech"}}},
+		{Slug: "blank", Name: "Blank", Options: []AnswerOption{{Label: "Fintech"}, {Label: " "}}},
+		{Name: "No slug", Options: threeWay.Options},
+	}
+
+	for _, pack := range invalid {
+		if err := pack.Validate(); !errors.Is(err, ErrInvalidPack) {
+			t.Fatal(pack, err)
+		}
+	}
+}
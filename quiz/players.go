@@ -0,0 +1,106 @@
+package quiz
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var (
+	ErrInvalidUsername    = errors.New("invalid username")
+	ErrWeakPassword       = errors.New("password too weak")
+	ErrInvalidCredentials = errors.New("wrong username or password")
+)
+
+// MinPasswordLength is the shortest password players can register with.
+const MinPasswordLength = 8
+
+// SessionLifetime is how long a player stays logged in.
+const SessionLifetime = 30 * 24 * time.Hour
+
+var usernameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{3,20}$`)
+
+// Player is a registered player. Their games are linked to them by id, and
+// nobody else can play under their username.
+type Player struct {
+	Id           int64     `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	Created      time.Time `json:"created"`
+}
+
+// Session keeps a player logged in. The token is what the player's browser
+// holds, and is only ever compared, never shown.
+type Session struct {
+	Token    string
+	PlayerId int64
+	Created  time.Time
+	Expires  time.Time
+}
+
+// IsExpired reports whether the session has run out at now.
+func (session Session) IsExpired(now time.Time) bool {
+	return !now.Before(session.Expires)
+}
+
+// NewSessionToken makes a random token that cannot be guessed.
+func NewSessionToken() string {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+// ValidateUsername checks a username before registering it: 3 to 20
+// letters, digits, dashes or underscores.
+func ValidateUsername(username string) error {
+	if !usernameRegex.MatchString(username) {
+		return fmt.Errorf("%w: use 3 to 20 letters, digits, - or _", ErrInvalidUsername)
+	}
+	return nil
+}
+
+// ValidatePassword checks a password before registering it.
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("%w: use at least %d characters", ErrWeakPassword, MinPasswordLength)
+	}
+	return nil
+}
+
+// PersonalBests picks the player's best completed game in each of the modes
+// they have finished, ranked like the mode's leaderboard, in the order of
+// modes.
+func PersonalBests(games []Game, modes Modes) []Game {
+	best := map[string]Game{}
+	for _, game := range games {
+		if game.InProgress {
+			continue
+		}
+
+		current, ok := best[game.Mode]
+		if mode := modes.For(game); !ok || rank(game, mode) > rank(current, mode) {
+			best[game.Mode] = game
+		}
+	}
+
+	var bests []Game
+	for _, mode := range modes {
+		if game, ok := best[mode.Name]; ok {
+			bests = append(bests, game)
+		}
+	}
+	return bests
+}
+
+// rank is what a game is ranked by on its mode's leaderboard.
+func rank(game Game, mode GameMode) int64 {
+	if mode.RanksByStreak() {
+		return game.BestStreak
+	}
+	return game.Score
+}
@@ -0,0 +1,89 @@
+package quiz
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$v=19$") || strings.Contains(hash, "correct horse") {
+		t.Fatal(hash)
+	}
+
+	if !CheckPassword(hash, "correct horse") {
+		t.Fatal("the password should match its hash")
+	}
+
+	if CheckPassword(hash, "battery staple") {
+		t.Fatal("another password should not match")
+	}
+
+	if other, _ := HashPassword("correct horse"); other == hash {
+		t.Fatal("hashes should be salted")
+	}
+}
+
+func TestCheckPassword_Malformed(t *testing.T) {
+	for _, hash := range []string{"", "plain", "$argon2i$v=19$m=65536,t=3,p=4$c2FsdA$aGFzaA", "$argon2id$v=19$m=x,t=3,p=4$c2FsdA$aGFzaA"} {
+		if CheckPassword(hash, "plain") {
+			t.Fatal(hash)
+		}
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	for _, username := range []string{"bob", "Alice_99", "mary-jane"} {
+		if err := ValidateUsername(username); err != nil {
+			t.Fatal(username, err)
+		}
+	}
+
+	for _, username := range []string{"", "al", "bob smith", "<script>", strings.Repeat("a", 21)} {
+		if err := ValidateUsername(username); !errors.Is(err, ErrInvalidUsername) {
+			t.Fatal(username, err)
+		}
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	if err := ValidatePassword("short"); !errors.Is(err, ErrWeakPassword) {
+		t.Fatal(err)
+	}
+
+	if err := ValidatePassword("long enough"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSession_IsExpired(t *testing.T) {
+	now := time.Now()
+	session := Session{Expires: now.Add(time.Minute)}
+
+	if session.IsExpired(now) || !session.IsExpired(now.Add(time.Minute)) {
+		t.Fatal(session)
+	}
+}
+
+func TestPersonalBests(t *testing.T) {
+	games := []Game{
+		{Mode: "quick", Score: 3},
+		{Mode: "classic", Score: 6},
+		{Mode: "classic", Score: 9},
+		{Mode: "classic", Score: 10, InProgress: true},
+		{Mode: "survival", Score: 1, BestStreak: 7},
+		{Mode: "survival", Score: 9, BestStreak: 2},
+	}
+
+	bests := PersonalBests(games, GameModes)
+
+	if len(bests) != 3 || bests[0].Mode != "classic" || bests[0].Score != 9 || bests[1].Mode != "quick" || bests[2].BestStreak != 7 {
+		t.Fatal(bests)
+	}
+}
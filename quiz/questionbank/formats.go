@@ -0,0 +1,102 @@
+package questionbank
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var csvHeader = []string{"word", "answer", "source", "notes", "pack"}
+
+// readCSV expects a header row naming the columns. word and answer are
+// required; source, notes and pack may be left out.
+func readCSV(reader io.Reader) ([]Record, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, required := range []string{"word", "answer"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv header should include a %q column", required)
+		}
+	}
+
+	field := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return row[i]
+	}
+
+	var records []Record
+	for {
+		row, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, Record{
+			Word:   field(row, "word"),
+			Answer: field(row, "answer"),
+			Source: field(row, "source"),
+			Notes:  field(row, "notes"),
+			Pack:   field(row, "pack"),
+		})
+	}
+
+	return records, nil
+}
+
+func writeCSV(writer io.Writer, records []Record) error {
+	csvWriter := csv.NewWriter(writer)
+
+	if err := csvWriter.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := csvWriter.Write([]string{record.Word, record.Answer, record.Source, record.Notes, record.Pack}); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// readJSON expects an array of records.
+func readJSON(reader io.Reader) ([]Record, error) {
+	var records []Record
+	if err := json.NewDecoder(reader).Decode(&records); err != nil {
+		return nil, fmt.Errorf("json pack should be an array of records: %w", err)
+	}
+
+	return records, nil
+}
+
+func writeJSON(writer io.Writer, records []Record) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(records)
+}
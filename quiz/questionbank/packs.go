@@ -0,0 +1,134 @@
+package questionbank
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"me885/fintech-or-furniture/quiz"
+	"strings"
+)
+
+// PackRecord is one pack as it appears in a packs file, which is a JSON
+// array of them, such as
+//
+//	[{"slug": "fintech-furniture-or-pharma", "name": "Fintech, Furniture or Pharma",
+//	  "options": [{"label": "Fintech"}, {"label": "Furniture"}, {"label": "Pharma"}]}]
+type PackRecord struct {
+	Slug    string         `json:"slug"`
+	Name    string         `json:"name"`
+	Options []OptionRecord `json:"options"`
+}
+
+type OptionRecord struct {
+	Label string `json:"label"`
+}
+
+type PackReport struct {
+	Created   []string
+	Updated   []string
+	Unchanged []string
+	// Kept are options in the bank that the file leaves out, as "slug:
+	// label". They stay in their packs, as questions may be answered with
+	// them.
+	Kept []string
+}
+
+func (r PackReport) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d packs created, %d updated, %d unchanged", len(r.Created), len(r.Updated), len(r.Unchanged))
+
+	for _, option := range r.Kept {
+		fmt.Fprintf(&b, "\nkept: %s is not in the file but options cannot be removed", option)
+	}
+
+	return b.String()
+}
+
+// ReadPacks parses and validates every pack in a packs file. Nothing is
+// returned if any pack is invalid.
+func ReadPacks(reader io.Reader) ([]quiz.Pack, error) {
+	var records []PackRecord
+	if err := json.NewDecoder(reader).Decode(&records); err != nil {
+		return nil, fmt.Errorf("packs file should be a json array of packs: %w", err)
+	}
+
+	var packs []quiz.Pack
+	var problems []error
+	for i, record := range records {
+		pack := record.toPack()
+		if err := pack.Validate(); err != nil {
+			problems = append(problems, fmt.Errorf("pack %d: %w", i+1, err))
+			continue
+		}
+		packs = append(packs, pack)
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+
+	return packs, nil
+}
+
+func (r PackRecord) toPack() quiz.Pack {
+	pack := quiz.Pack{Slug: strings.TrimSpace(r.Slug), Name: strings.TrimSpace(r.Name)}
+	for _, option := range r.Options {
+		pack.Options = append(pack.Options, quiz.AnswerOption{Label: strings.TrimSpace(option.Label)})
+	}
+	return pack
+}
+
+// ImportPacks reads a packs file and adds its packs to the repository. Packs
+// already in the bank, matched by slug, are renamed and given any options
+// they are missing. Running the same file twice leaves the bank unchanged.
+func ImportPacks(repository quiz.Repository, reader io.Reader) (*PackReport, error) {
+	packs, err := ReadPacks(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PackReport{}
+
+	for _, pack := range packs {
+		existing, err := repository.GetPackBySlug(pack.Slug)
+		if errors.Is(err, quiz.ErrNotExists) {
+			if _, err := repository.CreatePack(pack); err != nil {
+				return report, fmt.Errorf("%s: %w", pack.Slug, err)
+			}
+			report.Created = append(report.Created, pack.Slug)
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("%s: %w", pack.Slug, err)
+		}
+
+		update := *existing
+		update.Name = pack.Name
+		update.Options = append([]quiz.AnswerOption(nil), existing.Options...)
+		for _, option := range pack.Options {
+			if _, err := existing.ParseAnswer(option.Label); err != nil {
+				update.Options = append(update.Options, option)
+			}
+		}
+
+		for _, option := range existing.Options {
+			if _, err := pack.ParseAnswer(option.Label); err != nil {
+				report.Kept = append(report.Kept, pack.Slug+": "+option.Label)
+			}
+		}
+
+		if update.Name == existing.Name && len(update.Options) == len(existing.Options) {
+			report.Unchanged = append(report.Unchanged, pack.Slug)
+			continue
+		}
+
+		if _, err := repository.UpdatePack(update); err != nil {
+			return report, fmt.Errorf("%s: %w", pack.Slug, err)
+		}
+		report.Updated = append(report.Updated, pack.Slug)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,75 @@
+package questionbank_test
+
+import (
+	"me885/fintech-or-furniture/quiz"
+	"me885/fintech-or-furniture/quiz/database"
+	"me885/fintech-or-furniture/quiz/questionbank"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const packs = `[
+  {"slug": "fintech-furniture-or-pharma", "name": "Fintech, Furniture or Pharma",
+   "options": [{"label": "Fintech"}, {"label": "Furniture"}, {"label": "Pharma"}]},
+  {"slug": "fintech-or-furniture", "name": "Fintech or Furniture",
+   "options": [{"label": "Fintech"}, {"label": "Furniture"}]}
+]`
+
+func TestImportPacks(t *testing.T) {
+	repo := database.NewMemoryRepository()
+
+	report, err := questionbank.ImportPacks(repo, strings.NewReader(packs))
+	if err != nil || !reflect.DeepEqual(report.Created, []string{"fintech-furniture-or-pharma"}) || !reflect.DeepEqual(report.Unchanged, []string{quiz.DefaultPack.Slug}) {
+		t.Fatal(report, err)
+	}
+
+	pack, err := repo.GetPackBySlug("fintech-furniture-or-pharma")
+	if err != nil || pack.LabelList() != "Fintech, Furniture or Pharma" {
+		t.Fatal(pack, err)
+	}
+
+	// Questions can be imported into the new pack straight away.
+	imported, err := questionbank.Import(repo, strings.NewReader("word,answer,pack\nPFIZER,pharma,fintech-furniture-or-pharma\n"), questionbank.CSV)
+	if err != nil || len(imported.Created) != 1 {
+		t.Fatal(imported, err)
+	}
+
+	report, err = questionbank.ImportPacks(repo, strings.NewReader(packs))
+	if err != nil || len(report.Unchanged) != 2 {
+		t.Fatal("importing again should change nothing", report, err)
+	}
+}
+
+func TestImportPacks_UpdatesExisting(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	questionbank.ImportPacks(repo, strings.NewReader(packs))
+	before, _ := repo.GetPackBySlug("fintech-furniture-or-pharma")
+
+	report, err := questionbank.ImportPacks(repo, strings.NewReader(`[{"slug": "fintech-furniture-or-pharma", "name": "Fintech, Furniture, Pharma or Pokémon",
+		"options": [{"label": "Fintech"}, {"label": "furniture"}, {"label": "Pokémon"}]}]`))
+	if err != nil || len(report.Updated) != 1 || !reflect.DeepEqual(report.Kept, []string{"fintech-furniture-or-pharma: Pharma"}) {
+		t.Fatal(report, err)
+	}
+
+	after, _ := repo.GetPackBySlug("fintech-furniture-or-pharma")
+	if after.Name != "Fintech, Furniture, Pharma or Pokémon" || len(after.Options) != 4 || !reflect.DeepEqual(after.Options[:3], before.Options) || after.Options[3].Label != "Pokémon" {
+		t.Fatal(after)
+	}
+}
+
+func TestImportPacks_InvalidImportsNothing(t *testing.T) {
+	repo := database.NewMemoryRepository()
+
+	_, err := questionbank.ImportPacks(repo, strings.NewReader(`[
+		{"slug": "crypto-or-cheese", "name": "Crypto or Cheese", "options": [{"label": "Crypto"}, {"label": "Cheese"}]},
+		{"slug": "lonely", "name": "Lonely", "options": [{"label": "Alone"}]}
+	]`))
+	if err == nil || !strings.Contains(err.Error(), "pack 2") {
+		t.Fatal(err)
+	}
+
+	if all, _ := repo.AllPacks(); len(all) != 1 {
+		t.Fatal(all)
+	}
+}
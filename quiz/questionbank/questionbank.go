@@ -0,0 +1,263 @@
+// Package questionbank loads question packs from CSV or JSON files into a
+// quiz.Repository and dumps the repository back out in the same formats.
+package questionbank
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"me885/fintech-or-furniture/quiz"
+	"path/filepath"
+	"strings"
+)
+
+type Format string
+
+const (
+	CSV  Format = "csv"
+	JSON Format = "json"
+)
+
+// FormatFromFilename picks the format from a .csv or .json extension.
+func FormatFromFilename(filename string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return CSV, nil
+	case ".json":
+		return JSON, nil
+	}
+
+	return "", fmt.Errorf("%s: question packs should be .csv or .json files", filename)
+}
+
+// Record is one question as it appears in a pack file. Answer is one of the
+// labels of the question's pack, which is given by slug and defaults to the
+// original Fintech or Furniture pack.
+type Record struct {
+	Word   string `json:"word"`
+	Answer string `json:"answer"`
+	Source string `json:"source,omitempty"`
+	Notes  string `json:"notes,omitempty"`
+	Pack   string `json:"pack,omitempty"`
+}
+
+// Conflict is a word that is already in the bank with a different answer or
+// in a different pack. It is reported rather than overwritten.
+type Conflict struct {
+	Word     string
+	Existing string
+	Imported string
+}
+
+type Report struct {
+	Created   []string
+	Updated   []string
+	Unchanged []string
+	// Duplicates are words that appear more than once in the pack; only the
+	// first occurrence is imported.
+	Duplicates []string
+	Conflicts  []Conflict
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d created, %d updated, %d unchanged, %d duplicates, %d conflicts",
+		len(r.Created), len(r.Updated), len(r.Unchanged), len(r.Duplicates), len(r.Conflicts))
+
+	for _, word := range r.Duplicates {
+		fmt.Fprintf(&b, "\nduplicate: %s appears more than once", word)
+	}
+	for _, conflict := range r.Conflicts {
+		fmt.Fprintf(&b, "\nconflict: %s is already %s, pack says %s", conflict.Word, conflict.Existing, conflict.Imported)
+	}
+
+	return b.String()
+}
+
+// Read parses and validates every record in a file against the given packs.
+// Nothing is returned if any record is invalid, so a bad file never half
+// imports.
+func Read(reader io.Reader, format Format, packs []quiz.Pack) ([]quiz.Question, error) {
+	var records []Record
+	var err error
+
+	switch format {
+	case CSV:
+		records, err = readCSV(reader)
+	case JSON:
+		records, err = readJSON(reader)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	packsBySlug := map[string]quiz.Pack{}
+	for _, pack := range packs {
+		packsBySlug[pack.Slug] = pack
+	}
+
+	var questions []quiz.Question
+	var problems []error
+	for i, record := range records {
+		question, err := record.toQuestion(packsBySlug)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("record %d: %w", i+1, err))
+			continue
+		}
+		questions = append(questions, question)
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+
+	return questions, nil
+}
+
+func (r Record) toQuestion(packs map[string]quiz.Pack) (quiz.Question, error) {
+	word := strings.TrimSpace(r.Word)
+	if word == "" {
+		return quiz.Question{}, errors.New("word is required")
+	}
+
+	slug := strings.TrimSpace(r.Pack)
+	if slug == "" {
+		slug = quiz.DefaultPack.Slug
+	}
+
+	pack, ok := packs[slug]
+	if !ok {
+		return quiz.Question{}, fmt.Errorf("%s: unknown pack %q", word, slug)
+	}
+
+	answer, err := pack.ParseAnswer(r.Answer)
+	if err != nil {
+		return quiz.Question{}, fmt.Errorf("%s: %w", word, err)
+	}
+
+	return quiz.Question{Question: word, Answer: answer, Source: strings.TrimSpace(r.Source), Notes: strings.TrimSpace(r.Notes), PackId: pack.Id}, nil
+}
+
+// Import reads a file of questions and upserts them into the repository.
+// Running the same file twice leaves the bank unchanged. The packs the
+// questions belong to must already exist.
+func Import(repository quiz.Repository, reader io.Reader, format Format) (*Report, error) {
+	packs, err := repository.AllPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	questions, err := Read(reader, format, packs)
+	if err != nil {
+		return nil, err
+	}
+
+	return Upsert(repository, questions)
+}
+
+func Upsert(repository quiz.Repository, questions []quiz.Question) (*Report, error) {
+	packs, err := packsById(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	// describe names the pack as well as the answer when they differ, as
+	// labels alone are ambiguous across packs.
+	describe := func(question *quiz.Question, other *quiz.Question) string {
+		label := packs[question.PackId].Label(question.Answer)
+		if question.PackId != other.PackId {
+			label += " in " + packs[question.PackId].Name
+		}
+		return label
+	}
+
+	report := &Report{}
+	seen := map[string]bool{}
+
+	for _, question := range questions {
+		if question.PackId == 0 {
+			question.PackId = quiz.DefaultPackId
+		}
+
+		if seen[question.Question] {
+			report.Duplicates = append(report.Duplicates, question.Question)
+			continue
+		}
+		seen[question.Question] = true
+
+		existing, err := repository.GetQuestionByText(question.Question)
+		if errors.Is(err, quiz.ErrNotExists) {
+			if _, err := repository.CreateQuestion(question); err != nil {
+				return report, fmt.Errorf("%s: %w", question.Question, err)
+			}
+			report.Created = append(report.Created, question.Question)
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("%s: %w", question.Question, err)
+		}
+
+		if existing.Answer != question.Answer || existing.PackId != question.PackId {
+			report.Conflicts = append(report.Conflicts, Conflict{Word: question.Question, Existing: describe(existing, &question), Imported: describe(&question, existing)})
+			continue
+		}
+
+		if existing.Source == question.Source && existing.Notes == question.Notes {
+			report.Unchanged = append(report.Unchanged, question.Question)
+			continue
+		}
+
+		question.Id = existing.Id
+		question.Disabled = existing.Disabled
+		if _, err := repository.UpdateQuestion(question); err != nil {
+			return report, fmt.Errorf("%s: %w", question.Question, err)
+		}
+		report.Updated = append(report.Updated, question.Question)
+	}
+
+	return report, nil
+}
+
+// Export writes every question in the repository in a form Import accepts.
+func Export(repository quiz.Repository, writer io.Writer, format Format) error {
+	packs, err := packsById(repository)
+	if err != nil {
+		return err
+	}
+
+	questions, err := repository.AllQuestions()
+	if err != nil {
+		return err
+	}
+
+	records := make([]Record, 0, len(questions))
+	for _, question := range questions {
+		pack := packs[question.PackId]
+		records = append(records, Record{Word: question.Question, Answer: pack.Label(question.Answer), Source: question.Source, Notes: question.Notes, Pack: pack.Slug})
+	}
+
+	switch format {
+	case CSV:
+		return writeCSV(writer, records)
+	case JSON:
+		return writeJSON(writer, records)
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func packsById(repository quiz.Repository) (map[int64]quiz.Pack, error) {
+	packs, err := repository.AllPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	byId := map[int64]quiz.Pack{}
+	for _, pack := range packs {
+		byId[pack.Id] = pack
+	}
+	return byId, nil
+}
@@ -0,0 +1,181 @@
+package questionbank_test
+
+import (
+	"bytes"
+	"me885/fintech-or-furniture/quiz"
+	"me885/fintech-or-furniture/quiz/database"
+	"me885/fintech-or-furniture/quiz/questionbank"
+	"strings"
+	"testing"
+)
+
+const pack = `word,answer,source,notes
+PAX,Furniture,Ikea,wardrobe
+ZYNGA,fintech,,
+KALLAX,Furniture,,
+PAX,Fintech,,
+`
+
+func TestImport(t *testing.T) {
+	repo := database.NewMemoryRepository()
+
+	report, err := questionbank.Import(repo, strings.NewReader(pack), questionbank.CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Created) != 3 || len(report.Duplicates) != 1 || report.Duplicates[0] != "PAX" {
+		t.Fatal(report)
+	}
+
+	question, err := repo.GetQuestionByText("PAX")
+	if err != nil || question.Answer != quiz.Furniture || question.Source != "Ikea" || question.Notes != "wardrobe" {
+		t.Fatal(question, err)
+	}
+}
+
+func TestImport_IsIdempotent(t *testing.T) {
+	repo := database.NewMemoryRepository()
+
+	questionbank.Import(repo, strings.NewReader(pack), questionbank.CSV)
+
+	report, err := questionbank.Import(repo, strings.NewReader(pack), questionbank.CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Created) != 0 || len(report.Updated) != 0 || len(report.Unchanged) != 3 {
+		t.Fatal(report)
+	}
+
+	count, _ := repo.CountQuestions()
+	if count != 3 {
+		t.Fatal(count)
+	}
+}
+
+func TestImport_UpdatesAndConflicts(t *testing.T) {
+	repo := database.NewMemoryRepository()
+
+	questionbank.Import(repo, strings.NewReader(pack), questionbank.CSV)
+
+	update := `[
+		{"word": "ZYNGA", "answer": "Fintech", "notes": "games"},
+		{"word": "KALLAX", "answer": "Fintech"}
+	]`
+
+	report, err := questionbank.Import(repo, strings.NewReader(update), questionbank.JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Updated) != 1 || report.Updated[0] != "ZYNGA" {
+		t.Fatal(report)
+	}
+
+	if len(report.Conflicts) != 1 || report.Conflicts[0] != (questionbank.Conflict{Word: "KALLAX", Existing: "Furniture", Imported: "Fintech"}) {
+		t.Fatal(report)
+	}
+
+	kallax, _ := repo.GetQuestionByText("KALLAX")
+	if kallax.Answer != quiz.Furniture {
+		t.Fatal(kallax)
+	}
+}
+
+func TestImport_InvalidAnswerImportsNothing(t *testing.T) {
+	repo := database.NewMemoryRepository()
+
+	invalid := "word,answer\nPAX,Furniture\nBANANA,Fruit\n,Fintech\n"
+
+	_, err := questionbank.Import(repo, strings.NewReader(invalid), questionbank.CSV)
+	if err == nil || !strings.Contains(err.Error(), "record 2") || !strings.Contains(err.Error(), "record 3") {
+		t.Fatal(err)
+	}
+
+	count, _ := repo.CountQuestions()
+	if count != 0 {
+		t.Fatal(count)
+	}
+}
+
+func TestImport_MissingColumn(t *testing.T) {
+	_, err := questionbank.Read(strings.NewReader("word\nPAX\n"), questionbank.CSV, []quiz.Pack{quiz.DefaultPack})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestImport_Packs(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	pharma, _ := repo.CreatePack(quiz.Pack{Slug: "pokemon-or-pharma", Name: "Pokémon or Pharma", Options: []quiz.AnswerOption{{Label: "Pokémon"}, {Label: "Pharma"}}})
+
+	report, err := questionbank.Import(repo, strings.NewReader("word,answer,pack\nXATU,pokémon,pokemon-or-pharma\nPAX,Furniture,\n"), questionbank.CSV)
+	if err != nil || len(report.Created) != 2 {
+		t.Fatal(report, err)
+	}
+
+	xatu, _ := repo.GetQuestionByText("XATU")
+	if xatu.PackId != pharma.Id || xatu.Answer != pharma.Options[0].Id {
+		t.Fatal(xatu)
+	}
+
+	pax, _ := repo.GetQuestionByText("PAX")
+	if pax.PackId != quiz.DefaultPackId || pax.Answer != quiz.Furniture {
+		t.Fatal(pax)
+	}
+
+	report, err = questionbank.Import(repo, strings.NewReader(`[{"word": "PAX", "answer": "Pharma", "pack": "pokemon-or-pharma"}]`), questionbank.JSON)
+	if err != nil || len(report.Conflicts) != 1 || report.Conflicts[0] != (questionbank.Conflict{Word: "PAX", Existing: "Furniture in Fintech or Furniture", Imported: "Pharma in Pokémon or Pharma"}) {
+		t.Fatal(report, err)
+	}
+}
+
+func TestImport_UnknownPackOrLabel(t *testing.T) {
+	repo := database.NewMemoryRepository()
+
+	_, err := questionbank.Import(repo, strings.NewReader("word,answer,pack\nXATU,Pokémon,pokemon-or-pharma\nPAX,Pharma,\n"), questionbank.CSV)
+	if err == nil || !strings.Contains(err.Error(), "unknown pack") || !strings.Contains(err.Error(), "record 2") {
+		t.Fatal(err)
+	}
+}
+
+func TestExport_RoundTrip(t *testing.T) {
+	for _, format := range []questionbank.Format{questionbank.CSV, questionbank.JSON} {
+		repo := database.NewMemoryRepository()
+		questionbank.Import(repo, strings.NewReader(pack), questionbank.CSV)
+
+		var exported bytes.Buffer
+		if err := questionbank.Export(repo, &exported, format); err != nil {
+			t.Fatal(err)
+		}
+
+		copyRepo := database.NewMemoryRepository()
+		report, err := questionbank.Import(copyRepo, &exported, format)
+		if err != nil || len(report.Created) != 3 {
+			t.Fatal(format, report, err)
+		}
+
+		original, _ := repo.AllQuestions()
+		imported, _ := copyRepo.AllQuestions()
+		for i := range original {
+			if original[i] != imported[i] {
+				t.Fatal(format, original[i], imported[i])
+			}
+		}
+	}
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	if format, err := questionbank.FormatFromFilename("pack.CSV"); err != nil || format != questionbank.CSV {
+		t.Fatal(format, err)
+	}
+
+	if format, err := questionbank.FormatFromFilename("pack.json"); err != nil || format != questionbank.JSON {
+		t.Fatal(format, err)
+	}
+
+	if _, err := questionbank.FormatFromFilename("pack.txt"); err == nil {
+		t.Fatal("expected error")
+	}
+}
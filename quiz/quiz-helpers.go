@@ -2,29 +2,68 @@ package quiz
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
-func HandleAnswer(answer string, question Question, game *Game) (bool, error) {
+var (
+	ErrNoCurrentQuestion  = errors.New("no question is awaiting an answer for this game")
+	ErrWrongQuestion      = errors.New("question does not match the one served to this game")
+	ErrOutOfQuestions     = errors.New("no questions left for this game")
+	ErrNotEnoughQuestions = errors.New("not enough questions in the bank for this mode")
+)
+
+// HandleAnswer scores an answer, the id of one of the pack's options, given
+// at now in a game played in mode. Answers after the question's deadline
+// count as wrong whatever they are.
+func HandleAnswer(answer Answer, pack Pack, question Question, game *Game, mode GameMode, now time.Time) (bool, error) {
+	if err := pack.CheckAnswer(answer); err != nil {
+		return false, err
+	}
 
 	game.QuestionsAnswered++
 
-	if answer == "Fintech" && question.Answer == Fintech {
-		game.Score++
-		return true, nil
-	} else if answer == "Furniture" && question.Answer == Furniture {
-		game.Score++
-		return true, nil
-	} else if answer == "Fintech" && question.Answer == Furniture {
-		return false, nil
-	} else if answer == "Furniture" && question.Answer == Fintech {
+	if answer != question.Answer || IsQuestionOutOfTime(game, mode, now) {
+		game.Mistakes++
+		game.Streak = 0
 		return false, nil
 	}
 
-	return false, errors.New("Answer should be 'Fintech' or 'Furniture'")
+	game.Score += answerPoints(game, mode, now)
+	game.Streak++
+	if game.Streak > game.BestStreak {
+		game.BestStreak = game.Streak
+	}
+	return true, nil
+}
+
+// MissQuestions counts questions the player ran out of time to answer, such
+// as in a room that moved on without them, as wrong answers.
+func MissQuestions(game *Game, count int64) {
+	game.QuestionsAnswered += count
+	game.Mistakes += count
+	game.Streak = 0
 }
 
-func IsGameComplete(game *Game) bool {
-	if game.QuestionsAnswered < 10 {
+// answerPoints is what a correct answer given at now scores. Under
+// ScoreSpeed an instant answer scores SpeedPoints and one on the deadline
+// scores 1.
+func answerPoints(game *Game, mode GameMode, now time.Time) int64 {
+	if mode.Scoring != ScoreSpeed || mode.QuestionTimeLimit == 0 {
+		return 1
+	}
+
+	left := game.QuestionTimeLeft(mode, now)
+	return 1 + int64((SpeedPoints-1)*left/mode.QuestionTimeLimit)
+}
+
+// IsGameComplete ends the game if it has run out of questions or lives in
+// mode, which should be the game's mode as the server offers it.
+func IsGameComplete(game *Game, mode GameMode) bool {
+	outOfQuestions := mode.QuestionCount > 0 && game.QuestionsAnswered >= mode.QuestionCount
+	outOfLives := mode.Lives > 0 && game.Mistakes >= mode.Lives
+
+	if !outOfQuestions && !outOfLives {
 		return false
 
 	} else {
@@ -33,3 +72,44 @@ func IsGameComplete(game *Game) bool {
 		return true
 	}
 }
+
+// IsOutOfTime reports whether the time limit of the game's mode has run out.
+// It does not end the game.
+func IsOutOfTime(game *Game, mode GameMode, now time.Time) bool {
+	return mode.TimeLimit > 0 && now.Sub(game.Created) > mode.TimeLimit
+}
+
+// IsQuestionOutOfTime reports whether the current question's time limit has
+// run out.
+func IsQuestionOutOfTime(game *Game, mode GameMode, now time.Time) bool {
+	limit := game.QuestionTimeLimit(mode)
+
+	return limit > 0 && now.Sub(game.QuestionServed) > limit
+}
+
+// CheckBankSize reports whether a bank of available questions is enough for a
+// full game in mode. Modes without a question count need at least one.
+func CheckBankSize(mode GameMode, available int64) error {
+	needed := mode.QuestionCount
+	if needed == 0 {
+		needed = 1
+	}
+
+	if available < needed {
+		return fmt.Errorf("%w: %s needs %d but there are %d", ErrNotEnoughQuestions, mode.Name, needed, available)
+	}
+
+	return nil
+}
+
+func CheckAnswerable(game *Game, questionId int64) error {
+	if game.CurrentQuestionId == 0 {
+		return ErrNoCurrentQuestion
+	}
+
+	if game.CurrentQuestionId != questionId {
+		return ErrWrongQuestion
+	}
+
+	return nil
+}
@@ -1,13 +1,15 @@
 package quiz
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type HandleAnswerTest struct {
-	answer                    string
+	answer                    Answer
 	questionAnswer            Answer
 	questionsAnswered         int64
 	expectedWasCorrect        bool
@@ -15,10 +17,10 @@ type HandleAnswerTest struct {
 }
 
 var handleAnswerHappyPaths = []HandleAnswerTest{
-	{"Fintech", Fintech, 4, true, 5},
-	{"Fintech", Furniture, 4, false, 5},
-	{"Furniture", Fintech, 4, false, 5},
-	{"Furniture", Furniture, 4, true, 5},
+	{Fintech, Fintech, 4, true, 5},
+	{Fintech, Furniture, 4, false, 5},
+	{Furniture, Fintech, 4, false, 5},
+	{Furniture, Furniture, 4, true, 5},
 }
 
 func TestHandleAnswer(t *testing.T) {
@@ -27,7 +29,7 @@ func TestHandleAnswer(t *testing.T) {
 		question := Question{Id: 1, Question: "google", Answer: v.questionAnswer}
 		game := &Game{Id: uuid.New(), PlayerName: "bob", QuestionsAnswered: v.questionsAnswered, Score: 4, InProgress: true}
 
-		wasCorrect, err := HandleAnswer(answer, question, game)
+		wasCorrect, err := HandleAnswer(answer, DefaultPack, question, game, GameModes.For(*game), time.Now())
 		if wasCorrect != v.expectedWasCorrect || game.QuestionsAnswered != v.expectedQuestionsAnswered || err != nil {
 			t.Fatal(wasCorrect, game.QuestionsAnswered, err, v)
 		}
@@ -35,12 +37,144 @@ func TestHandleAnswer(t *testing.T) {
 }
 
 func TestHandleAnswer_InvalidAnswer(t *testing.T) {
-	answer := "apple"
+	answer := Answer(99)
 	question := Question{Id: 1, Question: "google", Answer: Fintech}
 	game := &Game{Id: uuid.New(), PlayerName: "bob", QuestionsAnswered: 4, Score: 4, InProgress: true}
 
-	wasCorrect, err := HandleAnswer(answer, question, game)
-	if err == nil {
-		t.Fatal(wasCorrect)
+	wasCorrect, err := HandleAnswer(answer, DefaultPack, question, game, GameModes.For(*game), time.Now())
+	if !errors.Is(err, ErrInvalidAnswer) || game.QuestionsAnswered != 4 {
+		t.Fatal(wasCorrect, err, game)
+	}
+}
+
+func TestCheckAnswerable(t *testing.T) {
+	game := &Game{Id: uuid.New(), PlayerName: "bob", InProgress: true}
+
+	if err := CheckAnswerable(game, 1); err != ErrNoCurrentQuestion {
+		t.Fatal(err)
+	}
+
+	game.CurrentQuestionId = 2
+
+	if err := CheckAnswerable(game, 1); err != ErrWrongQuestion {
+		t.Fatal(err)
+	}
+
+	if err := CheckAnswerable(game, 2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsGameComplete_Modes(t *testing.T) {
+	quick := &Game{Id: uuid.New(), Mode: "quick", QuestionsAnswered: 5, InProgress: true}
+	if !IsGameComplete(quick, GameModes.For(*quick)) || quick.InProgress {
+		t.Fatal(quick)
+	}
+
+	classic := &Game{Id: uuid.New(), Mode: "classic", QuestionsAnswered: 5, InProgress: true}
+	if IsGameComplete(classic, GameModes.For(*classic)) || !classic.InProgress {
+		t.Fatal(classic)
+	}
+
+	marathon := &Game{Id: uuid.New(), Mode: "marathon", QuestionsAnswered: 4, Mistakes: 3, InProgress: true}
+	mode := GameModes.For(*marathon)
+	if !IsGameComplete(marathon, mode) || marathon.LivesLeft(mode) != 0 {
+		t.Fatal(marathon)
+	}
+}
+
+func TestIsOutOfTime(t *testing.T) {
+	created := time.Now()
+
+	blitz := &Game{Id: uuid.New(), Mode: "blitz", Created: created}
+	if IsOutOfTime(blitz, GameModes.For(*blitz), created.Add(30*time.Second)) {
+		t.Fatal(blitz)
+	}
+	if !IsOutOfTime(blitz, GameModes.For(*blitz), created.Add(2*time.Minute)) {
+		t.Fatal(blitz)
+	}
+
+	classic := &Game{Id: uuid.New(), Mode: "classic", Created: created}
+	if IsOutOfTime(classic, GameModes.For(*classic), created.Add(time.Hour)) {
+		t.Fatal(classic)
+	}
+}
+
+func TestModes_Get(t *testing.T) {
+	if mode, err := GameModes.Get("marathon"); err != nil || mode.Lives != 3 {
+		t.Fatal(mode, err)
+	}
+
+	if _, err := GameModes.Get("nonsense"); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
+
+func TestHandleAnswer_SpeedScoring(t *testing.T) {
+	served := time.Now()
+	question := Question{Id: 1, Question: "google", Answer: Fintech}
+
+	fast := &Game{Id: uuid.New(), Mode: "timed", InProgress: true, QuestionServed: served}
+	if wasCorrect, err := HandleAnswer(Fintech, DefaultPack, question, fast, GameModes.For(*fast), served.Add(time.Second)); !wasCorrect || err != nil || fast.Score != 9 {
+		t.Fatal(wasCorrect, err, fast)
+	}
+
+	slow := &Game{Id: uuid.New(), Mode: "timed", InProgress: true, QuestionServed: served}
+	if wasCorrect, err := HandleAnswer(Fintech, DefaultPack, question, slow, GameModes.For(*slow), served.Add(9*time.Second)); !wasCorrect || err != nil || slow.Score != 1 {
+		t.Fatal(wasCorrect, err, slow)
+	}
+}
+
+func TestHandleAnswer_AfterQuestionDeadline(t *testing.T) {
+	served := time.Now()
+	question := Question{Id: 1, Question: "google", Answer: Fintech}
+	game := &Game{Id: uuid.New(), Mode: "timed", InProgress: true, QuestionServed: served}
+
+	wasCorrect, err := HandleAnswer(Fintech, DefaultPack, question, game, GameModes.For(*game), served.Add(11*time.Second))
+	if wasCorrect || err != nil || game.Score != 0 || game.Mistakes != 1 || game.QuestionsAnswered != 1 {
+		t.Fatal(wasCorrect, err, game)
+	}
+}
+
+func TestHandleAnswer_Streaks(t *testing.T) {
+	question := Question{Id: 1, Question: "google", Answer: Fintech}
+	game := &Game{Id: uuid.New(), Mode: "classic", InProgress: true}
+
+	for _, answer := range []Answer{Fintech, Fintech, Furniture, Fintech} {
+		HandleAnswer(answer, DefaultPack, question, game, GameModes.For(*game), time.Now())
+	}
+
+	if game.Streak != 1 || game.BestStreak != 2 {
+		t.Fatal(game)
+	}
+}
+
+func TestIsGameComplete_Survival(t *testing.T) {
+	game := &Game{Id: uuid.New(), Mode: "survival", QuestionsAnswered: 100, Score: 100, InProgress: true}
+	if IsGameComplete(game, GameModes.For(*game)) {
+		t.Fatal(game)
+	}
+
+	game.Mistakes = 1
+	if !IsGameComplete(game, GameModes.For(*game)) || game.InProgress {
+		t.Fatal(game)
+	}
+}
+
+func TestCheckBankSize(t *testing.T) {
+	classic, _ := GameModes.Get("classic")
+	survival, _ := GameModes.Get("survival")
+
+	if err := CheckBankSize(classic, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckBankSize(classic, 9); !errors.Is(err, ErrNotEnoughQuestions) {
+		t.Fatal(err)
+	}
+	if err := CheckBankSize(survival, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckBankSize(survival, 0); !errors.Is(err, ErrNotEnoughQuestions) {
+		t.Fatal(err)
 	}
 }
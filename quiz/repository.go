@@ -0,0 +1,138 @@
+package quiz
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDuplicate    = errors.New("record already exists")
+	ErrNotExists    = errors.New("row not exists")
+	ErrUpdateFailed = errors.New("update failed")
+	ErrDeleteFailed = errors.New("delete failed")
+	ErrClosed       = errors.New("repository closed")
+)
+
+// Repository is the storage needed to run the quiz. Implementations return
+// ErrNotExists when a lookup finds nothing, ErrDuplicate when a question
+// already exists, ErrUpdateFailed when an update matches no rows and
+// ErrDeleteFailed when a delete matches no rows.
+//
+// Words are unique across the whole bank rather than per pack.
+type Repository interface {
+	CreatePack(pack Pack) (*Pack, error)
+	GetPackById(id int64) (*Pack, error)
+	GetPackBySlug(slug string) (*Pack, error)
+	AllPacks() ([]Pack, error)
+	// UpdatePack renames the pack and adds the options that have no id yet
+	// after its existing ones. Options already in the pack are kept as they
+	// are, since questions are answered with them. It returns ErrNotExists
+	// when there is no such pack and ErrDuplicate when a new option's label
+	// is taken.
+	UpdatePack(pack Pack) (*Pack, error)
+
+	CreateQuestion(question Question) (*Question, error)
+	GetQuestionById(id int64) (*Question, error)
+	GetQuestionByText(question string) (*Question, error)
+	UpdateQuestion(question Question) (*Question, error)
+	AllQuestions() ([]Question, error)
+	CountQuestions() (int64, error)
+	// CountEnabledQuestions counts the questions in a pack that can be served
+	// to games.
+	CountEnabledQuestions(packId int64) (int64, error)
+	// ListQuestions returns a page of questions whose word, source or notes
+	// contain search, ignoring case, along with the total number matching.
+	ListQuestions(search string, offset int, limit int) ([]Question, int64, error)
+	DeleteQuestion(id int64) error
+	// RecordAnswer adds an answer to the question's statistics. UpdateQuestion
+	// leaves the statistics alone, so edits in the admin console cannot lose
+	// answers recorded meanwhile.
+	RecordAnswer(questionId int64, correct bool) error
+
+	AddAuditEntry(entry AuditEntry) error
+	// CreateQuestionAudited, UpdateQuestionAudited and DeleteQuestionAudited
+	// make a change and add the audit entry for it, filling in the
+	// question, together, so that there is no change without a record of
+	// who made it.
+	CreateQuestionAudited(question Question, entry AuditEntry) (*Question, error)
+	UpdateQuestionAudited(question Question, entry AuditEntry) (*Question, error)
+	DeleteQuestionAudited(question Question, entry AuditEntry) error
+	AuditEntries(limit int) ([]AuditEntry, error)
+
+	// CreatePlayer returns ErrDuplicate when the username is taken, ignoring
+	// case.
+	CreatePlayer(username string, passwordHash string) (*Player, error)
+	GetPlayerById(id int64) (*Player, error)
+	// GetPlayerByUsername ignores case.
+	GetPlayerByUsername(username string) (*Player, error)
+	// PlayerGames returns the games linked to the player, newest first.
+	// Games are linked by having PlayerId set with UpdateGame.
+	PlayerGames(playerId int64) ([]Game, error)
+
+	// CreateSession logs a player in until expires, with a new random token.
+	CreateSession(playerId int64, expires time.Time) (*Session, error)
+	GetSession(token string) (*Session, error)
+	DeleteSession(token string) error
+
+	// CreateGameSession starts a session to play games in, with a new random
+	// token.
+	CreateGameSession(expires time.Time) (*GameSession, error)
+	GetGameSession(token string) (*GameSession, error)
+	// RotateGameSession gives the session a new random token and expiry,
+	// keeping its games.
+	RotateGameSession(id int64, expires time.Time) (*GameSession, error)
+	DeleteGameSession(token string) error
+	// GetSessionGame returns the session's game with the id, or its newest
+	// game when the id is uuid.Nil.
+	GetSessionGame(sessionId int64, gameId uuid.UUID) (*Game, error)
+
+	// CreateGame starts a game in progress, with a snapshot of its pack's
+	// question statistics. Games of a day's challenge get the seed everyone
+	// playing it shares, and CreateGame returns ErrDuplicate when the player
+	// name, ignoring case, has already played that day. Other games get a
+	// random seed. Games joining a room get ErrRoomStarted once it has
+	// left the lobby, and ErrDuplicate when a player in it already has the
+	// same name, ignoring case.
+	CreateGame(game NewGame) (*Game, error)
+	GetGameById(id uuid.UUID) (*Game, error)
+	UpdateGame(game *Game) (*Game, error)
+	AllGames() ([]Game, error)
+
+	// CreateRoom opens a room in the lobby with a random seed. It returns
+	// ErrDuplicate when the code is taken.
+	CreateRoom(code string, packId int64, hostGameId uuid.UUID) (*Room, error)
+	GetRoomByCode(code string) (*Room, error)
+	// UpdateRoom saves the room unless it has moved on since previous was
+	// read, that is changed state or asked another question, so that of two
+	// servers moving a room on at once only one does. It returns
+	// ErrUpdateFailed when the room has moved on or does not exist.
+	UpdateRoom(room *Room, previous Room) (*Room, error)
+	// RoomGames returns the games of a room's players in the order they
+	// joined. Games join a room by having its code set with UpdateGame.
+	RoomGames(code string) ([]Game, error)
+
+	AddGameQuestion(gameId uuid.UUID, questionId int64) error
+	RemoveGameQuestions(gameId uuid.UUID) error
+	// GetUnansweredQuestions returns the enabled questions from the game's
+	// pack that it has not been asked yet, ordered by id. Their statistics
+	// are as they were when the game was created, so that the game's picks
+	// depend only on its seed and answers.
+	GetUnansweredQuestions(gameId uuid.UUID) ([]Question, error)
+	ClaimCurrentQuestion(gameId uuid.UUID, questionId int64) error
+
+	TopTenCompletedGames(mode string, since time.Time) ([]Game, error)
+	// TopTenStreaks is like TopTenCompletedGames but ranks by BestStreak.
+	TopTenStreaks(mode string, since time.Time) ([]Game, error)
+	// TopTenDailyGames ranks the completed games of a day's challenge by
+	// score, then by who finished first.
+	TopTenDailyGames(day string) ([]Game, error)
+
+	// Ping checks the storage can still be reached, and fails once the
+	// repository is closed.
+	Ping(ctx context.Context) error
+	// Close releases the storage. Nothing else may be called afterwards.
+	Close() error
+}
@@ -0,0 +1,84 @@
+package quiz
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RoomState string
+
+const (
+	// RoomLobby is a room waiting for its host to start, which players can
+	// still join.
+	RoomLobby RoomState = "lobby"
+	// RoomPlaying is a room whose players are being asked questions.
+	RoomPlaying RoomState = "playing"
+	// RoomFinished is a room whose players' games have all ended.
+	RoomFinished RoomState = "finished"
+)
+
+// ErrRoomStarted is returned for players joining a room that has left the
+// lobby.
+var ErrRoomStarted = errors.New("room has already started")
+
+// RoomQuestionTimeLimit is how long players in a room have to answer each
+// question. Once it runs out the room moves on without the players who have
+// not answered, so one player leaving does not hold up everyone else.
+const RoomQuestionTimeLimit = 20 * time.Second
+
+// RoomCodeLength is how many characters room codes have. They leave out
+// letters and digits that are easily mixed up, such as O and 0.
+const (
+	RoomCodeLength   = 5
+	roomCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// Room is a head to head game. Each player has their own game in the room,
+// played by the rules of DefaultMode, and the room serves every player the
+// same question at the same time.
+type Room struct {
+	Code       string    `json:"code"`
+	PackId     int64     `json:"packId"`
+	HostGameId uuid.UUID `json:"hostGameId"`
+	State      RoomState `json:"state"`
+	// CurrentQuestionId is the question the players are being asked, 0
+	// before the room starts.
+	CurrentQuestionId int64 `json:"currentQuestionId"`
+	QuestionsAsked    int64 `json:"questionsAsked"`
+	// QuestionServed is when the current question was asked.
+	QuestionServed time.Time `json:"questionServed"`
+	// Seed is where the room's choice of questions starts from, like a
+	// game's.
+	Seed    int64     `json:"seed"`
+	Created time.Time `json:"created"`
+}
+
+// IsQuestionOutOfTime reports whether the players have run out of time to
+// answer the room's current question.
+func (room Room) IsQuestionOutOfTime(now time.Time) bool {
+	return room.State == RoomPlaying && now.Sub(room.QuestionServed) > RoomQuestionTimeLimit
+}
+
+// QuestionRand is the source the room's next question is picked with.
+func (room Room) QuestionRand() *rand.Rand {
+	return rand.New(rand.NewSource(room.Seed + room.QuestionsAsked))
+}
+
+// NewRoomCode makes a random room code. Codes are short enough to read out,
+// so callers should try another when one is already taken.
+func NewRoomCode() string {
+	var code strings.Builder
+	for i := 0; i < RoomCodeLength; i++ {
+		code.WriteByte(roomCodeAlphabet[rand.Intn(len(roomCodeAlphabet))])
+	}
+	return code.String()
+}
+
+// NormaliseRoomCode tidies a code typed in by a player.
+func NormaliseRoomCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
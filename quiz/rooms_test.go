@@ -0,0 +1,35 @@
+package quiz
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewRoomCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code := NewRoomCode()
+
+		if len(code) != RoomCodeLength || strings.Trim(code, roomCodeAlphabet) != "" {
+			t.Fatal(code)
+		}
+	}
+}
+
+func TestNormaliseRoomCode(t *testing.T) {
+	if code := NormaliseRoomCode(" ab2cd\n"); code != "AB2CD" {
+		t.Fatal(code)
+	}
+}
+
+func TestRoom_QuestionRand(t *testing.T) {
+	room := Room{Seed: 42, QuestionsAsked: 3}
+
+	if room.QuestionRand().Int63() != room.QuestionRand().Int63() {
+		t.Fatal("the same room should pick the same way")
+	}
+
+	next := Room{Seed: 42, QuestionsAsked: 4}
+	if room.QuestionRand().Int63() == next.QuestionRand().Int63() {
+		t.Fatal("each question should be picked with a different source")
+	}
+}
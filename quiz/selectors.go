@@ -0,0 +1,122 @@
+package quiz
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Selector picks a game's next question from those it has not been asked
+// yet. questions is never empty and is ordered by id, and rng is seeded from
+// the game, so a selector whose only randomness is rng picks the same way
+// every time the game is replayed.
+type Selector interface {
+	Select(questions []Question, game Game, rng *rand.Rand) Question
+}
+
+// DefaultSelector is used when nothing else is configured.
+var DefaultSelector Selector = DifficultySelector{}
+
+// ParseSelector reads a selector from its name in settings: "difficulty",
+// "uniform", "balanced" or "sequence:" followed by comma separated question
+// ids. An empty name is DefaultSelector.
+func ParseSelector(name string) (Selector, error) {
+	switch name {
+	case "":
+		return DefaultSelector, nil
+	case "difficulty":
+		return DifficultySelector{}, nil
+	case "uniform":
+		return UniformSelector{}, nil
+	case "balanced":
+		return BalancedSelector{}, nil
+	}
+
+	ids, ok := strings.CutPrefix(name, "sequence:")
+	if !ok {
+		return nil, fmt.Errorf("unknown selector %q, expected difficulty, uniform, balanced or sequence:<ids>", name)
+	}
+
+	var selector SequenceSelector
+	for _, id := range strings.Split(ids, ",") {
+		parsed, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("selector %q: %q is not a question id", name, id)
+		}
+		selector.QuestionIds = append(selector.QuestionIds, parsed)
+	}
+	return selector, nil
+}
+
+// QuestionRand is the source a game's next question is picked with. It
+// depends only on the game's seed and how far it has got.
+func (game Game) QuestionRand() *rand.Rand {
+	return rand.New(rand.NewSource(game.Seed + game.QuestionsAnswered))
+}
+
+// UniformSelector picks any of the questions with equal chance.
+type UniformSelector struct{}
+
+func (UniformSelector) Select(questions []Question, game Game, rng *rand.Rand) Question {
+	return questions[rng.Intn(len(questions))]
+}
+
+// DifficultySelector picks at random among the SelectionPool questions whose
+// difficulty is closest to the game's TargetDifficulty, so games get harder
+// as the player scores. Difficulty is read from the snapshot of the pack's
+// question statistics taken when the game was created, so answers recorded
+// since, by anyone, do not change which questions the game is asked.
+type DifficultySelector struct{}
+
+func (DifficultySelector) Select(questions []Question, game Game, rng *rand.Rand) Question {
+	target := TargetDifficulty(game)
+
+	candidates := append([]Question(nil), questions...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return math.Abs(candidates[i].Difficulty()-target) < math.Abs(candidates[j].Difficulty()-target)
+	})
+
+	pool := min(SelectionPool, len(candidates))
+	return candidates[rng.Intn(pool)]
+}
+
+// BalancedSelector picks one of the answers the questions have with equal
+// chance, then a question with that answer, so each answer comes up about
+// as often however lopsided the bank is.
+type BalancedSelector struct{}
+
+func (BalancedSelector) Select(questions []Question, game Game, rng *rand.Rand) Question {
+	var answers []Answer
+	byAnswer := map[Answer][]Question{}
+	for _, question := range questions {
+		if byAnswer[question.Answer] == nil {
+			answers = append(answers, question.Answer)
+		}
+		byAnswer[question.Answer] = append(byAnswer[question.Answer], question)
+	}
+
+	class := byAnswer[answers[rng.Intn(len(answers))]]
+	return class[rng.Intn(len(class))]
+}
+
+// SequenceSelector asks questions in a fixed order: the first of
+// QuestionIds still to be asked, or once none are left the remaining
+// question with the lowest id.
+type SequenceSelector struct {
+	QuestionIds []int64
+}
+
+func (selector SequenceSelector) Select(questions []Question, game Game, rng *rand.Rand) Question {
+	for _, id := range selector.QuestionIds {
+		for _, question := range questions {
+			if question.Id == id {
+				return question
+			}
+		}
+	}
+
+	return questions[0]
+}
@@ -0,0 +1,115 @@
+package quiz
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestGame_QuestionRand(t *testing.T) {
+	game := Game{Seed: 42, QuestionsAnswered: 3}
+
+	if game.QuestionRand().Int63() != game.QuestionRand().Int63() {
+		t.Fatal("the same game should pick the same way")
+	}
+
+	next := Game{Seed: 42, QuestionsAnswered: 4}
+	if game.QuestionRand().Int63() == next.QuestionRand().Int63() {
+		t.Fatal("each question should be picked with a different source")
+	}
+}
+
+func TestDifficultySelector_RampsUp(t *testing.T) {
+	var questions []Question
+	for correct := int64(0); correct <= 10; correct++ {
+		questions = append(questions, Question{Id: correct, TimesServed: 10, TimesCorrect: correct})
+	}
+
+	for seed := int64(0); seed < 20; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+
+		beginner := DifficultySelector{}.Select(questions, Game{}, rng)
+		expert := DifficultySelector{}.Select(questions, Game{QuestionsAnswered: 12}, rng)
+
+		// The three questions closest to each target are answered right 7
+		// to 9 times and 0 to 2 times out of 10.
+		if beginner.TimesCorrect < 7 || expert.TimesCorrect > 2 {
+			t.Fatal(seed, beginner, expert)
+		}
+	}
+}
+
+func TestBalancedSelector(t *testing.T) {
+	questions := []Question{{Id: 1, Answer: Furniture}}
+	for id := int64(2); id <= 10; id++ {
+		questions = append(questions, Question{Id: id, Answer: Fintech})
+	}
+
+	selector := BalancedSelector{}
+	rng := rand.New(rand.NewSource(1))
+
+	furniture := 0
+	for i := 0; i < 1000; i++ {
+		if selector.Select(questions, Game{}, rng).Answer == Furniture {
+			furniture++
+		}
+	}
+
+	if furniture < 400 || furniture > 600 {
+		t.Fatal(furniture)
+	}
+}
+
+func TestSequenceSelector(t *testing.T) {
+	selector := SequenceSelector{QuestionIds: []int64{3, 1}}
+
+	for _, test := range []struct {
+		remaining []int64
+		expected  int64
+	}{{[]int64{1, 2, 3}, 3}, {[]int64{1, 2}, 1}, {[]int64{2, 4}, 2}} {
+		var questions []Question
+		for _, id := range test.remaining {
+			questions = append(questions, Question{Id: id})
+		}
+
+		if question := selector.Select(questions, Game{}, nil); question.Id != test.expected {
+			t.Fatal(test, question)
+		}
+	}
+}
+
+func TestUniformSelector_SameSeedSamePicks(t *testing.T) {
+	var questions []Question
+	for id := int64(1); id <= 30; id++ {
+		questions = append(questions, Question{Id: id})
+	}
+
+	game := Game{Seed: 7}
+	first := UniformSelector{}.Select(questions, game, game.QuestionRand())
+	again := UniformSelector{}.Select(questions, game, game.QuestionRand())
+
+	if first != again {
+		t.Fatal(first, again)
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	for name, expected := range map[string]Selector{
+		"":                DefaultSelector,
+		"difficulty":      DifficultySelector{},
+		"uniform":         UniformSelector{},
+		"balanced":        BalancedSelector{},
+		"sequence:3, 1,2": SequenceSelector{QuestionIds: []int64{3, 1, 2}},
+	} {
+		selector, err := ParseSelector(name)
+		if err != nil || !reflect.DeepEqual(selector, expected) {
+			t.Fatal(name, selector, err)
+		}
+	}
+
+	for _, name := range []string{"random", "sequence:", "sequence:1,x", "sequence:0"} {
+		if _, err := ParseSelector(name); err == nil {
+			t.Fatal(name, "should not parse")
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package quiz
+
+import "time"
+
+// GameSessionLifetime is how long a browser keeps its games without starting
+// a new one. Every new game renews the session.
+const GameSessionLifetime = 7 * 24 * time.Hour
+
+// GameSession ties the games played in one browser, or by one API client, to
+// the random token it holds in a cookie. The token changes with every new
+// game, but the session keeps its id and so its earlier games.
+type GameSession struct {
+	Id      int64
+	Token   string
+	Created time.Time
+	Expires time.Time
+}
+
+// IsExpired reports whether the session has run out at now.
+func (session GameSession) IsExpired(now time.Time) bool {
+	return !now.Before(session.Expires)
+}